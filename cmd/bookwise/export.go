@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bookwise/api/internal/services"
+)
+
+// runExport writes the saved books as CSV, JSON Lines or MARCXML, the same
+// output as GET /api/v1/export/books
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", services.ExportFormatCSV, "output format: "+strings.Join(services.ExportFormats, ", "))
+	output := flags.String("output", "-", "output file, - for stdout")
+	includeQuizzes := flags.Bool("include-quizzes", false, "add each book's active quiz (csv and jsonl)")
+	includeAnswers := flags.Bool("include-answers", false, "keep answers, explanations, keywords and rubrics in exported quizzes")
+	author := flags.String("author", "", "only books with an author containing this text")
+	category := flags.String("category", "", "only books with a category containing this text")
+	language := flags.String("language", "", "only books in this language")
+	quizStatus := flags.String("quiz-status", "", "only books with this quiz status: "+strings.Join(services.BookQuizStatuses, ", "))
+	flags.Parse(args)
+
+	if *quizStatus != "" && !slices.Contains(services.BookQuizStatuses, *quizStatus) {
+		return fmt.Errorf("-quiz-status must be one of: %s", strings.Join(services.BookQuizStatuses, ", "))
+	}
+
+	opts := services.BookExportOptions{
+		Format: *format,
+		Filter: services.BookFilter{
+			Author:     *author,
+			Category:   *category,
+			Language:   *language,
+			QuizStatus: *quizStatus,
+		},
+		IncludeQuizzes: *includeQuizzes,
+		QuizAnswers:    *includeAnswers,
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := setup(); err != nil {
+		return err
+	}
+	defer teardown()
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *output != "-" {
+		var err error
+		if file, err = os.Create(*output); err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	count, err := services.ExportBooks(ctx, buffered, opts)
+	if err != nil {
+		return fmt.Errorf("export failed after %d books: %w", count, err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		log.Printf("✅ Exported %d books to %s", count, *output)
+	}
+	return nil
+}
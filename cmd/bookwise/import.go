@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bookwise/api/internal/isbn"
+	"github.com/bookwise/api/internal/models"
+	"github.com/bookwise/api/internal/services"
+	"github.com/google/uuid"
+)
+
+// runImport imports books by ISBN. Every processed ISBN is appended to a
+// checkpoint file (JSON lines); a rerun skips ISBNs already in it, except
+// failed ones, so an interrupted import can be resumed.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	file := flags.String("file", "", "CSV or text file with ISBNs (\"isbn\" column or one per line), - for stdin")
+	concurrency := flags.Int("concurrency", 0, "ISBNs looked up at once (default IMPORT_CONCURRENCY)")
+	generateQuiz := flags.Bool("generate-quiz", false, "enqueue quiz generation for imported books with a pending quiz")
+	checkpoint := flags.String("checkpoint", "", "checkpoint file for resuming (default <file>.checkpoint, none for stdin)")
+	flags.Parse(args)
+
+	if *file == "" {
+		flags.Usage()
+		return errors.New("-file is required")
+	}
+	if *checkpoint == "" && *file != "-" {
+		*checkpoint = *file + ".checkpoint"
+	}
+
+	inputs, err := readISBNFile(*file)
+	if err != nil {
+		return err
+	}
+
+	done, err := loadCheckpoint(*checkpoint)
+	if err != nil {
+		return err
+	}
+	remaining := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		if !done[checkpointKey(input)] {
+			remaining = append(remaining, input)
+		}
+	}
+	if skipped := len(inputs) - len(remaining); skipped > 0 {
+		log.Printf("⏭️ Skipping %d ISBNs already in checkpoint %s", skipped, *checkpoint)
+	}
+	if len(remaining) == 0 {
+		log.Println("✅ Nothing to import")
+		return nil
+	}
+
+	cfg, err := setup()
+	if err != nil {
+		return err
+	}
+	defer teardown()
+
+	bookMerger, err := newBookMerger(cfg)
+	if err != nil {
+		return err
+	}
+
+	var record func(services.ImportResult) error
+	if *checkpoint != "" {
+		checkpointFile, err := os.OpenFile(*checkpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open checkpoint: %w", err)
+		}
+		defer checkpointFile.Close()
+		encoder := json.NewEncoder(checkpointFile)
+		record = func(result services.ImportResult) error {
+			return encoder.Encode(result)
+		}
+	}
+
+	opts := services.BookImportOptions{Concurrency: *concurrency}
+	if *generateQuiz {
+		opts.EnqueueQuiz = func(bookID uuid.UUID) (*models.QuizJob, error) {
+			return services.EnqueueQuizJob(bookID, cfg.Quiz.JobMaxAttempts, services.QuizJobOptions{})
+		}
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	var checkpointErr error
+	var once sync.Once
+	results := bookMerger.ImportISBNs(ctx, remaining, opts, func(result services.ImportResult) {
+		printImportResult(result)
+		if record == nil {
+			return
+		}
+		if err := record(result); err != nil {
+			once.Do(func() { checkpointErr = err })
+		}
+	})
+
+	summary := services.SummarizeImport(results)
+	fmt.Println()
+	for _, status := range services.ImportStatuses {
+		fmt.Printf("%-10s %d\n", status, summary[status])
+	}
+
+	switch {
+	case checkpointErr != nil:
+		return fmt.Errorf("failed to write checkpoint: %w", checkpointErr)
+	case ctx.Err() != nil:
+		return fmt.Errorf("import interrupted after %d of %d ISBNs, run again to resume", len(results), len(remaining))
+	case summary[services.ImportStatusFailed] > 0:
+		return fmt.Errorf("%d ISBNs failed, run again to retry them", summary[services.ImportStatusFailed])
+	}
+	return nil
+}
+
+// readISBNFile reads the ISBN list from path, or stdin for "-"
+func readISBNFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ISBN file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	inputs, err := services.ParseISBNList(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ISBN file: %w", err)
+	}
+	return inputs, nil
+}
+
+// loadCheckpoint returns the keys of ISBNs a previous run finished. Failed
+// ISBNs are not finished, so they are retried.
+func loadCheckpoint(path string) (map[string]bool, error) {
+	done := map[string]bool{}
+	if path == "" {
+		return done, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var result services.ImportResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			// A run killed mid-write may leave a truncated last line
+			log.Printf("⚠️ Ignoring malformed checkpoint line %d: %v", line, err)
+			continue
+		}
+		if result.Status != services.ImportStatusFailed {
+			done[checkpointKey(result.Input)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	return done, nil
+}
+
+// checkpointKey identifies an input ISBN regardless of its form
+func checkpointKey(input string) string {
+	if parsed, err := isbn.Parse(input); err == nil {
+		return parsed.ISBN13
+	}
+	return isbn.Normalize(input)
+}
+
+// printImportResult prints one line per processed ISBN
+func printImportResult(result services.ImportResult) {
+	id := result.ISBN
+	if id == "" {
+		id = result.Input
+	}
+
+	detail := result.Title
+	if result.JobID != nil {
+		detail += fmt.Sprintf(" (quiz job %s)", result.JobID)
+	}
+	if result.Error != "" {
+		detail += " " + result.Error
+	}
+
+	fmt.Printf("%-10s %-13s %s\n", result.Status, id, strings.TrimSpace(detail))
+}
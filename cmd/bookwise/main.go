@@ -0,0 +1,102 @@
+// Command bookwise runs maintenance tasks against the Bookwise database,
+// sharing the API server's configuration (.env / environment variables).
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/services"
+)
+
+const usage = `Usage: bookwise <command> [flags]
+
+Commands:
+  import   Import books by ISBN from a CSV or text file
+  export   Export saved books as CSV, JSON Lines or MARCXML
+
+Run "bookwise <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command := os.Args[1]; command {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// setup loads the configuration and connects to the database
+func setup() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := database.InitDatabase(cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	if err := database.AutoMigrate(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// teardown closes the connections opened by setup and newBookMerger
+func teardown() {
+	database.CloseRedis()
+	database.CloseDatabase()
+}
+
+// newBookMerger creates the book merger service like the API server does
+func newBookMerger(cfg *config.Config) (*services.BookMergerService, error) {
+	if cfg.SearchCache.Enabled && cfg.SearchCache.Backend == "redis" {
+		if err := database.InitRedis(cfg); err != nil {
+			return nil, fmt.Errorf("failed to initialize redis: %w", err)
+		}
+	}
+
+	providers, err := services.NewProvidersFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize book providers: %w", err)
+	}
+	cache, err := services.NewSearchCacheFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize search cache: %w", err)
+	}
+
+	merger, err := services.NewBookMergerService(providers, cache, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize merge policy: %w", err)
+	}
+	return merger, nil
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/bookwise/api/config"
 	"github.com/bookwise/api/internal/database"
 	"github.com/bookwise/api/internal/handlers"
+	"github.com/bookwise/api/internal/middleware"
+	"github.com/bookwise/api/internal/models"
 	"github.com/bookwise/api/internal/services"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -35,12 +37,36 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Connect to Redis when a feature uses it
+	if (cfg.RateLimit.Enabled && cfg.RateLimit.Backend == "redis") ||
+		(cfg.SearchCache.Enabled && cfg.SearchCache.Backend == "redis") {
+		if err := database.InitRedis(cfg); err != nil {
+			log.Fatalf("Failed to initialize redis: %v", err)
+		}
+	}
+
 	// Initialize services
-	bookMerger := services.NewBookMergerService(cfg.APIs.GoogleBooksAPIKey)
-	quizWorker := services.NewQuizWorker(cfg, 3) // 3 concurrent workers
+	bookProviders, err := services.NewProvidersFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize book providers: %v", err)
+	}
+	searchCache, err := services.NewSearchCacheFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize search cache: %v", err)
+	}
+	bookMerger, err := services.NewBookMergerService(bookProviders, searchCache, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize merge policy: %v", err)
+	}
+	quizWorker, err := services.NewQuizWorker(cfg, 3) // 3 concurrent workers
+	if err != nil {
+		log.Fatalf("Failed to initialize quiz worker: %v", err)
+	}
 
 	// Start quiz worker
-	quizWorker.Start()
+	if err := quizWorker.Start(); err != nil {
+		log.Fatalf("Failed to start quiz worker: %v", err)
+	}
 	defer quizWorker.Stop()
 
 	// Process any pending quizzes on startup
@@ -49,20 +75,50 @@ func main() {
 	// Start periodic retry for failed quizzes (every 1 hour)
 	quizWorker.StartPeriodicRetry(1 * time.Hour)
 
+	// Initialize authentication
+	authenticator, err := middleware.NewAuthenticator(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+	requireAuth := authenticator.RequireAuth()
+	readAuth := authenticator.ReadAuth()
+	requireEditor := middleware.RequireRole(models.RoleEditor)
+	requireAdmin := middleware.RequireRole(models.RoleAdmin)
+
+	// Initialize rate limiting
+	rateLimiter, err := middleware.NewRateLimiter(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limiting: %v", err)
+	}
+	limitSearch := rateLimiter.Limit("search")
+	limitWrite := rateLimiter.Limit("write")
+	limitGenerateQuiz := rateLimiter.Limit("generate_quiz")
+	limitExport := rateLimiter.Limit("export")
+
 	// Initialize handlers
 	booksHandler := handlers.NewBooksHandler(bookMerger, quizWorker)
-	quizHandler := handlers.NewQuizHandler()
+	quizHandler := handlers.NewQuizHandler(quizWorker, rateLimiter)
 	healthHandler := handlers.NewHealthHandler(quizWorker)
+	jobsHandler := handlers.NewJobsHandler(quizWorker)
+	usersHandler := handlers.NewUsersHandler()
+	adminHandler := handlers.NewAdminHandler(quizWorker)
+	exportHandler := handlers.NewExportHandler()
 
 	// Create router
 	router := gin.Default()
 
+	// Client IPs (and so per-IP rate limits) only come from forwarding
+	// headers set by trusted proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.Server.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-Cache"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -72,24 +128,65 @@ func main() {
 	router.GET("/health/detailed", healthHandler.DetailedHealth)
 
 	// API v1 routes
-	v1 := router.Group("/api/v1")
+	v1 := router.Group("/api/v1", rateLimiter.Limit("default")) // Per-IP limit for every API route
 	{
-		// Books routes
+		// Books routes (writes require a token, reads are public unless AUTH_PUBLIC_READS=false)
 		books := v1.Group("/books")
 		{
-			books.GET("/search", booksHandler.SearchBook)      // GET /api/v1/books/search?q=...&type=...&limit=...
-			books.POST("", booksHandler.SaveBook)              // POST /api/v1/books (body: {isbn, generate_quiz})
-			books.GET("", booksHandler.ListBooks)              // GET /api/v1/books?page=1&limit=10
-			books.GET("/:id", booksHandler.GetBookByID)        // GET /api/v1/books/:id
-			books.POST("/:id/generate-quiz", booksHandler.GenerateQuiz) // POST /api/v1/books/:id/generate-quiz
-			books.GET("/isbn/:isbn", booksHandler.GetBookByISBN) // GET /api/v1/books/isbn/:isbn
+			books.GET("/search", readAuth, limitSearch, booksHandler.SearchBook)     // GET /api/v1/books/search?q=...&type=...&limit=...&source=...
+			books.POST("", requireAuth, limitWrite, booksHandler.SaveBook)           // POST /api/v1/books (body: {isbn, generate_quiz} or a manual entry)
+			books.POST("/bulk", requireAuth, requireEditor, limitWrite, booksHandler.BulkImportBooks) // POST /api/v1/books/bulk (body: ISBN list as JSON or CSV)
+			books.GET("", readAuth, booksHandler.ListBooks)                          // GET /api/v1/books?page=1&limit=10&sort=...&cursor=...&q=...
+			books.GET("/:id", readAuth, booksHandler.GetBookByID)                    // GET /api/v1/books/:id
+			books.PATCH("/:id", requireAuth, requireEditor, limitWrite, booksHandler.UpdateBook)          // PATCH /api/v1/books/:id (body: fields to correct)
+			books.DELETE("/:id", requireAuth, requireAdmin, limitWrite, booksHandler.DeleteBook)         // DELETE /api/v1/books/:id
+			books.POST("/:id/refresh", requireAuth, requireEditor, limitWrite, booksHandler.RefreshBook) // POST /api/v1/books/:id/refresh?apply=true
+			books.POST("/:id/generate-quiz", requireAuth, limitGenerateQuiz, booksHandler.GenerateQuiz) // POST /api/v1/books/:id/generate-quiz?force=true
+			books.GET("/:id/quizzes", readAuth, quizHandler.ListQuizVersions)        // GET /api/v1/books/:id/quizzes?difficulty=...&lang=...
+			books.POST("/:id/quizzes/:quizId/activate", requireAuth, requireEditor, limitWrite, quizHandler.ActivateQuizVersion) // POST /api/v1/books/:id/quizzes/:quizId/activate
+			books.GET("/isbn/:isbn", readAuth, booksHandler.GetBookByISBN)           // GET /api/v1/books/isbn/:isbn
 		}
 
 		// Quiz routes
 		quiz := v1.Group("/quiz")
 		{
-			quiz.GET("/:bookId", quizHandler.GetQuiz)       // GET /api/v1/quiz/:bookId
-			quiz.GET("/id/:id", quizHandler.GetQuizByID)    // GET /api/v1/quiz/id/:id
+			quiz.GET("/:bookId", readAuth, quizHandler.GetQuiz)                 // GET /api/v1/quiz/:bookId?view=play|full&difficulty=...&lang=...
+			quiz.GET("/id/:id", readAuth, quizHandler.GetQuizByID)              // GET /api/v1/quiz/id/:id?view=play|full
+			quiz.POST("/:id/attempts", requireAuth, limitWrite, quizHandler.SubmitAttempt) // POST /api/v1/quiz/:id/attempts (body: {answers})
+		}
+
+		// Quiz generation job routes (error details are shown to editors only)
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("", readAuth, jobsHandler.ListJobs)                     // GET /api/v1/jobs?status=...&book_id=...
+			jobs.GET("/:id", readAuth, jobsHandler.GetJob)                   // GET /api/v1/jobs/:id
+			jobs.POST("/:id/cancel", requireAuth, requireEditor, limitWrite, jobsHandler.CancelJob) // POST /api/v1/jobs/:id/cancel
+			jobs.POST("/:id/retry", requireAuth, requireEditor, limitWrite, jobsHandler.RetryJob)   // POST /api/v1/jobs/:id/retry
+		}
+
+		// Export routes
+		export := v1.Group("/export")
+		{
+			export.GET("/books", readAuth, limitExport, exportHandler.ExportBooks) // GET /api/v1/export/books?format=csv|jsonl|marcxml&include=quizzes&view=play|full
+		}
+
+		// User routes
+		users := v1.Group("/users")
+		{
+			users.GET("/me", requireAuth, usersHandler.GetMe) // GET /api/v1/users/me
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin", requireAuth, requireAdmin)
+		{
+			admin.POST("/quizzes/retry-failed", adminHandler.RetryFailedQuizzes)       // POST /api/v1/admin/quizzes/retry-failed
+			admin.POST("/quizzes/process-pending", adminHandler.ProcessPendingQuizzes) // POST /api/v1/admin/quizzes/process-pending
+			admin.POST("/quizzes/reset-failed", adminHandler.ResetFailedQuizzes)       // POST /api/v1/admin/quizzes/reset-failed
+			admin.GET("/worker", adminHandler.GetWorker)                               // GET /api/v1/admin/worker
+			admin.POST("/worker/start", adminHandler.StartWorker)                      // POST /api/v1/admin/worker/start
+			admin.POST("/worker/stop", adminHandler.StopWorker)                        // POST /api/v1/admin/worker/stop
+			admin.GET("/users", adminHandler.ListUsers)                                // GET /api/v1/admin/users?role=...
+			admin.PATCH("/users/:id/role", adminHandler.UpdateUserRole)                // PATCH /api/v1/admin/users/:id/role
 		}
 	}
 
@@ -97,18 +194,51 @@ func main() {
 	log.Println("\n📚 Bookwise API Routes:")
 	log.Println("  GET   /health")
 	log.Println("  GET   /health/detailed")
-	log.Println("  GET   /api/v1/books/search?q={query}&type={isbn|title|author}&limit={limit}")
-	log.Println("  POST  /api/v1/books (body: {isbn, generate_quiz})")
-	log.Println("  GET   /api/v1/books")
+	log.Println("  GET   /api/v1/books/search?q={query}&type={isbn|title|author}&limit={limit}&source={local|external|both}")
+	log.Println("  POST  /api/v1/books (body: {isbn, generate_quiz, [data_sources, title, authors, ...]})")
+	log.Println("  POST  /api/v1/books/bulk?generate_quiz={true|false} (body: [isbn, ...] or CSV)")
+	log.Println("  GET   /api/v1/books?q={query}&sort={field}&order={asc|desc}&cursor={cursor}")
 	log.Println("  GET   /api/v1/books/:id")
-	log.Println("  POST  /api/v1/books/:id/generate-quiz")
+	log.Println("  PATCH /api/v1/books/:id")
+	log.Println("  DELETE /api/v1/books/:id")
+	log.Println("  POST  /api/v1/books/:id/refresh?apply={true|false}")
+	log.Println("  POST  /api/v1/books/:id/generate-quiz?force={true|false}")
+	log.Println("  GET   /api/v1/books/:id/quizzes?difficulty={easy|medium|hard}&lang={en|tr}")
+	log.Println("  POST  /api/v1/books/:id/quizzes/:quizId/activate")
 	log.Println("  GET   /api/v1/books/isbn/:isbn")
-	log.Println("  GET   /api/v1/quiz/:bookId")
-	log.Println("  GET   /api/v1/quiz/id/:id")
+	log.Println("  GET   /api/v1/quiz/:bookId?view={play|full}&difficulty={easy|medium|hard}&lang={en|tr}")
+	log.Println("  GET   /api/v1/quiz/id/:id?view={play|full}")
+	log.Println("  POST  /api/v1/quiz/:id/attempts (body: {answers})")
+	log.Println("  GET   /api/v1/jobs?status={status}&book_id={id}")
+	log.Println("  GET   /api/v1/jobs/:id")
+	log.Println("  POST  /api/v1/jobs/:id/cancel")
+	log.Println("  POST  /api/v1/jobs/:id/retry")
+	log.Println("  GET   /api/v1/export/books?format={csv|jsonl|marcxml}&include={quizzes}&view={play|full}")
+	log.Println("  GET   /api/v1/users/me")
+	log.Println("  POST  /api/v1/admin/quizzes/retry-failed")
+	log.Println("  POST  /api/v1/admin/quizzes/process-pending")
+	log.Println("  POST  /api/v1/admin/quizzes/reset-failed")
+	log.Println("  GET   /api/v1/admin/worker")
+	log.Println("  POST  /api/v1/admin/worker/start")
+	log.Println("  POST  /api/v1/admin/worker/stop")
+	log.Println("  GET   /api/v1/admin/users?role={role}")
+	log.Println("  PATCH /api/v1/admin/users/:id/role")
 
 	// Print worker stats
 	quizWorker.PrettyPrintStats()
 
+	// Reload quiz prompt templates on SIGHUP
+	go func() {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		for range hupChan {
+			log.Println("🔄 SIGHUP received, reloading quiz prompt templates...")
+			if err := quizWorker.ReloadPrompts(); err != nil {
+				log.Printf("❌ Failed to reload quiz prompt templates, keeping the current ones: %v", err)
+			}
+		}
+	}()
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -120,7 +250,8 @@ func main() {
 		// Stop quiz worker
 		quizWorker.Stop()
 		
-		// Close database connection
+		// Close database connections
+		database.CloseRedis()
 		database.CloseDatabase()
 		
 		log.Println("✅ Shutdown complete")
@@ -5,23 +5,37 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Gemini   GeminiConfig
-	APIs     ExternalAPIsConfig
-	Redis    RedisConfig
-	Quiz     QuizConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Gemini      GeminiConfig
+	OpenAI      OpenAIConfig
+	APIs        ExternalAPIsConfig
+	Redis       RedisConfig
+	Quiz        QuizConfig
+	Merge       MergeConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig
+	SearchCache SearchCacheConfig
+	LocalSearch LocalSearchConfig
+	Import      ImportConfig
 }
 
 type ServerConfig struct {
 	Port       string
 	GinMode    string
 	AllowedOrigins []string
+
+	// TrustedProxies are the proxy IPs/CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed (TRUSTED_PROXIES). Empty trusts none,
+	// so per-IP rate limits use the connection's address.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -38,19 +52,124 @@ type GeminiConfig struct {
 	Model  string
 }
 
+// OpenAIConfig configures the OpenAI-compatible quiz LLM backend
+type OpenAIConfig struct {
+	BaseURL string // e.g. http://localhost:11434/v1 for Ollama
+	APIKey  string // Optional for local servers
+	Model   string
+}
+
 type ExternalAPIsConfig struct {
 	GoogleBooksAPIKey string
+	BookProviders     []string // Ordered by merge priority
+
+	// SearchTimeout bounds a whole fan-out search across all providers,
+	// ProviderTimeout bounds each provider unless overridden in ProviderTimeouts
+	SearchTimeout    time.Duration
+	ProviderTimeout  time.Duration
+	ProviderTimeouts map[string]time.Duration
+}
+
+// MergeConfig controls how book data from multiple providers is merged
+type MergeConfig struct {
+	FieldPriority         map[string][]string // field -> provider order
+	DescriptionStrategy   string              // "priority" or "longest"
+	CategoriesStrategy    string              // "priority" or "union"
+	PublishedDateStrategy string              // "priority" or "earliest"
+}
+
+// AuthConfig controls JWT authentication. HS256 tokens are verified with
+// JWTSecret, RS256 tokens with keys from a JWKS file or URL (e.g. Firebase:
+// https://www.googleapis.com/service_accounts/v1/jwk/securetoken@system.gserviceaccount.com).
+type AuthConfig struct {
+	Enabled     bool
+	JWTSecret   string
+	JWKSFile    string
+	JWKSURL     string
+	JWKSRefresh time.Duration // How often keys from JWKSURL are reloaded
+	Issuer      string        // Expected "iss" claim, optional
+	Audience    string        // Expected "aud" claim, optional
+	PublicReads bool          // Allow GET endpoints without a token
+
+	// AdminSubjects are token subjects ("sub") always granted the admin
+	// role, used to bootstrap the first admin
+	AdminSubjects []string
 }
 
 type RedisConfig struct {
 	Host     string
 	Port     string
 	Password string
+	DB       int
+}
+
+// Addr returns the Redis host:port address
+func (c *RedisConfig) Addr() string {
+	return c.Host + ":" + c.Port
+}
+
+// RateLimitRule allows Limit requests per Period, refilled continuously
+// (token bucket with capacity Limit)
+type RateLimitRule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitConfig controls request rate limiting
+type RateLimitConfig struct {
+	Enabled bool
+	Backend string                   // "memory", "redis" or "postgres"
+	Rules   map[string]RateLimitRule // Rule name -> limit, see RATE_LIMIT_RULES
+}
+
+// SearchCacheConfig controls caching of external provider search results
+type SearchCacheConfig struct {
+	Enabled     bool
+	Backend     string        // "memory" or "redis"
+	TTL         time.Duration // Lifetime of cached results
+	NegativeTTL time.Duration // Lifetime of cached "not found" answers
+	MaxEntries  int           // Memory backend LRU capacity
+}
+
+// LocalSearchConfig controls full-text search over stored books
+type LocalSearchConfig struct {
+	// With source=both, external providers are skipped when at least
+	// MinResults local hits (or the requested limit, if lower) rank at
+	// least MinRank (0-1)
+	MinResults int
+	MinRank    float64
+}
+
+// ImportConfig controls bulk ISBN imports
+type ImportConfig struct {
+	Concurrency int // ISBNs looked up at once
+	MaxBatch    int // Max ISBNs per POST /books/bulk request
 }
 
 type QuizConfig struct {
 	QuestionsCount int
 	RetryLimit     int
+	LLMProvider    string        // "gemini", "openai" or "fake"
+	LLMTimeout     time.Duration // Per generation call
+
+	// QuestionTypes weights the question types of a generated quiz,
+	// e.g. {"multiple_choice": 3, "true_false": 1}
+	QuestionTypes map[string]int
+
+	// MinQualityScore rejects generated quizzes scoring lower (0-100) on the
+	// quality checks, so they are generated again; 0 only scores
+	MinQualityScore int
+
+	// PromptDir holds the quiz.<language>.tmpl prompt templates, reloaded on SIGHUP
+	PromptDir string
+
+	// Persistent job queue settings
+	JobMaxAttempts  int
+	JobPollInterval time.Duration
+	JobLockTimeout  time.Duration // Running jobs locked longer than this are requeued
+
+	// LLMDailyBudget caps LLM calls per UTC day across all replicas (0 = unlimited)
+	LLMDailyBudget int
 }
 
 var AppConfig *Config
@@ -69,6 +188,7 @@ func LoadConfig() (*Config, error) {
 			AllowedOrigins: []string{
 				getEnv("ALLOWED_ORIGINS", "http://localhost:3000"),
 			},
+			TrustedProxies: getEnvAsSlice("TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -82,25 +202,100 @@ func LoadConfig() (*Config, error) {
 			APIKey: getEnv("GEMINI_API_KEY", ""),
 			Model:  getEnv("GEMINI_MODEL", "gemini-1.5-flash"),
 		},
+		OpenAI: OpenAIConfig{
+			BaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			APIKey:  getEnv("OPENAI_API_KEY", ""),
+			Model:   getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		},
 		APIs: ExternalAPIsConfig{
 			GoogleBooksAPIKey: getEnv("GOOGLE_BOOKS_API_KEY", ""),
+			BookProviders:     getEnvAsSlice("BOOK_PROVIDERS", []string{"google_books", "open_library"}),
+			SearchTimeout:     getEnvAsDuration("BOOK_SEARCH_TIMEOUT", 15*time.Second),
+			ProviderTimeout:   getEnvAsDuration("BOOK_PROVIDER_TIMEOUT", 10*time.Second),
+			ProviderTimeouts:  getEnvAsDurationMap("BOOK_PROVIDER_TIMEOUTS"),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
 		Quiz: QuizConfig{
 			QuestionsCount: getEnvAsInt("QUIZ_QUESTIONS_COUNT", 5),
 			RetryLimit:     getEnvAsInt("QUIZ_RETRY_LIMIT", 3),
+			LLMProvider:    getEnv("QUIZ_LLM_PROVIDER", "gemini"),
+			LLMTimeout:     getEnvAsDuration("QUIZ_LLM_TIMEOUT", 60*time.Second),
+			QuestionTypes:  getEnvAsWeights("QUIZ_QUESTION_TYPES", map[string]int{"multiple_choice": 1}),
+			PromptDir:      getEnv("QUIZ_PROMPT_DIR", "prompts"),
+
+			MinQualityScore: getEnvAsInt("QUIZ_MIN_QUALITY_SCORE", 60),
+
+			JobMaxAttempts:  getEnvAsInt("QUIZ_JOB_MAX_ATTEMPTS", 3),
+			JobPollInterval: getEnvAsDuration("QUIZ_JOB_POLL_INTERVAL", 2*time.Second),
+			JobLockTimeout:  getEnvAsDuration("QUIZ_JOB_LOCK_TIMEOUT", 10*time.Minute),
+
+			LLMDailyBudget: getEnvAsInt("QUIZ_LLM_DAILY_BUDGET", 0),
+		},
+		Merge: MergeConfig{
+			FieldPriority:         getEnvAsPriorityMap("MERGE_FIELD_PRIORITY"),
+			DescriptionStrategy:   getEnv("MERGE_DESCRIPTION_STRATEGY", "priority"),
+			CategoriesStrategy:    getEnv("MERGE_CATEGORIES_STRATEGY", "priority"),
+			PublishedDateStrategy: getEnv("MERGE_PUBLISHED_DATE_STRATEGY", "priority"),
+		},
+		Auth: AuthConfig{
+			Enabled:     getEnvAsBool("AUTH_ENABLED", true),
+			JWTSecret:   getEnv("AUTH_JWT_SECRET", ""),
+			JWKSFile:    getEnv("AUTH_JWKS_FILE", ""),
+			JWKSURL:     getEnv("AUTH_JWKS_URL", ""),
+			JWKSRefresh: getEnvAsDuration("AUTH_JWKS_REFRESH", 1*time.Hour),
+			Issuer:      getEnv("AUTH_JWT_ISSUER", ""),
+			Audience:    getEnv("AUTH_JWT_AUDIENCE", ""),
+			PublicReads: getEnvAsBool("AUTH_PUBLIC_READS", true),
+
+			AdminSubjects: getEnvAsSlice("AUTH_ADMIN_SUBJECTS", nil),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Backend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+			Rules: getEnvAsRateLimitRules("RATE_LIMIT_RULES", map[string]RateLimitRule{
+				"default":       {Limit: 300, Period: time.Minute},
+				"search":        {Limit: 30, Period: time.Minute},
+				"write":         {Limit: 60, Period: time.Minute},
+				"generate_quiz": {Limit: 10, Period: time.Hour},
+				"export":        {Limit: 10, Period: time.Hour},
+			}),
+		},
+		SearchCache: SearchCacheConfig{
+			Enabled:     getEnvAsBool("SEARCH_CACHE_ENABLED", true),
+			Backend:     getEnv("SEARCH_CACHE_BACKEND", "memory"),
+			TTL:         getEnvAsDuration("SEARCH_CACHE_TTL", 6*time.Hour),
+			NegativeTTL: getEnvAsDuration("SEARCH_CACHE_NEGATIVE_TTL", 10*time.Minute),
+			MaxEntries:  getEnvAsInt("SEARCH_CACHE_MAX_ENTRIES", 1000),
+		},
+		LocalSearch: LocalSearchConfig{
+			MinResults: getEnvAsInt("LOCAL_SEARCH_MIN_RESULTS", 5),
+			MinRank:    getEnvAsFloat("LOCAL_SEARCH_MIN_RANK", 0.3),
+		},
+		Import: ImportConfig{
+			Concurrency: getEnvAsInt("IMPORT_CONCURRENCY", 4),
+			MaxBatch:    getEnvAsInt("IMPORT_MAX_BATCH", 500),
 		},
 	}
 
 	// Validate required fields
-	if config.Gemini.APIKey == "" {
+	if config.Quiz.LLMProvider == "gemini" && config.Gemini.APIKey == "" {
 		log.Println("Warning: GEMINI_API_KEY is not set")
 	}
 
+	// The quiz worker ticks on these; zero or negative values would panic
+	// or busy-loop against the database
+	if config.Quiz.JobPollInterval <= 0 {
+		return nil, fmt.Errorf("QUIZ_JOB_POLL_INTERVAL must be positive, got %v", config.Quiz.JobPollInterval)
+	}
+	if config.Quiz.JobLockTimeout <= 0 {
+		return nil, fmt.Errorf("QUIZ_JOB_LOCK_TIMEOUT must be positive, got %v", config.Quiz.JobLockTimeout)
+	}
+
 	AppConfig = config
 	return config, nil
 }
@@ -128,3 +323,141 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	values := []string{}
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsDurationMap parses "name=duration" pairs, e.g. "google_books=5s,open_library=8s"
+func getEnvAsDurationMap(key string) map[string]time.Duration {
+	values := map[string]time.Duration{}
+	for _, pair := range getEnvAsSlice(key, nil) {
+		name, durationStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			log.Printf("Warning: ignoring malformed %s entry %q: %v", key, pair, err)
+			continue
+		}
+		values[strings.TrimSpace(name)] = duration
+	}
+	return values
+}
+
+// getEnvAsPriorityMap parses "field=provider|provider" pairs,
+// e.g. "publisher=open_library|google_books,cover_url=open_library"
+func getEnvAsPriorityMap(key string) map[string][]string {
+	values := map[string][]string{}
+	for _, pair := range getEnvAsSlice(key, nil) {
+		field, providersStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+
+		providers := []string{}
+		for _, provider := range strings.Split(providersStr, "|") {
+			if provider = strings.TrimSpace(provider); provider != "" {
+				providers = append(providers, provider)
+			}
+		}
+		values[strings.TrimSpace(field)] = providers
+	}
+	return values
+}
+
+// getEnvAsWeights parses "name=weight" pairs, e.g.
+// "multiple_choice=3,true_false=1". A bare name has weight 1. The defaults
+// are used when the variable is unset or has no valid entry.
+func getEnvAsWeights(key string, defaults map[string]int) map[string]int {
+	weights := map[string]int{}
+	for _, pair := range getEnvAsSlice(key, nil) {
+		name, weightStr, ok := strings.Cut(pair, "=")
+		weight := 1
+		if ok {
+			var err error
+			if weight, err = strconv.Atoi(strings.TrimSpace(weightStr)); err != nil || weight < 0 {
+				log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+				continue
+			}
+		}
+		if weight > 0 {
+			weights[strings.TrimSpace(name)] = weight
+		}
+	}
+
+	if len(weights) == 0 {
+		return defaults
+	}
+	return weights
+}
+
+// getEnvAsRateLimitRules parses "name=limit/period" pairs, e.g.
+// "search=30/1m,generate_quiz=10/1h". Listed rules override the defaults.
+func getEnvAsRateLimitRules(key string, defaults map[string]RateLimitRule) map[string]RateLimitRule {
+	rules := make(map[string]RateLimitRule, len(defaults))
+	for name, rule := range defaults {
+		rules[name] = rule
+	}
+
+	for _, pair := range getEnvAsSlice(key, nil) {
+		name, ruleStr, ok := strings.Cut(pair, "=")
+		limitStr, periodStr, ok2 := strings.Cut(ruleStr, "/")
+		if !ok || !ok2 {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		if err != nil || limit < 1 {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+		if err != nil || period <= 0 {
+			log.Printf("Warning: ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		rules[strings.TrimSpace(name)] = RateLimitRule{Limit: limit, Period: period}
+	}
+	return rules
+}
@@ -3,8 +3,10 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/isbn"
 	"github.com/bookwise/api/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -49,15 +51,49 @@ func InitDatabase(cfg *config.Config) error {
 // AutoMigrate runs automatic migration for models
 func AutoMigrate() error {
 	log.Println("Running database migrations...")
+
+	if err := dropLegacyQuizBookIndex(); err != nil {
+		return fmt.Errorf("failed to migrate quiz book index: %w", err)
+	}
 	
 	err := DB.AutoMigrate(
 		&models.Book{},
 		&models.Quiz{},
+		&models.QuizJob{},
+		&models.QuizJobAttempt{},
+		&models.QuizAttempt{},
+		&models.User{},
+		&models.RateLimitBucket{},
+		&models.LLMUsage{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Quizzes created before versioning become version 1
+	if err := DB.Exec("UPDATE quizzes SET version = 1 WHERE version = 0 AND status = 'completed'").Error; err != nil {
+		return fmt.Errorf("failed to backfill quiz versions: %w", err)
+	}
+
+	// Quizzes created before generation parameters get their question count
+	if err := DB.Exec(`UPDATE quizzes SET questions_count = CASE jsonb_typeof(questions)
+		WHEN 'array' THEN jsonb_array_length(questions)
+		ELSE COALESCE(jsonb_array_length(questions->'quiz'), 0) END
+		WHERE questions_count = 0 AND status = 'completed'`).Error; err != nil {
+		return fmt.Errorf("failed to backfill quiz question counts: %w", err)
+	}
+
+	// Books saved before ISBN normalization (hyphenated, ISBN-13 in isbn,
+	// ...) get the stored form once, so the unique indexes catch duplicates
+	if err := normalizeBookISBNs(); err != nil {
+		return fmt.Errorf("failed to normalize book ISBNs: %w", err)
+	}
+
+	// Full-text search over stored books
+	if err := migrateBookSearch(); err != nil {
+		return fmt.Errorf("failed to migrate book search: %w", err)
+	}
+
 	// Create indexes
 	if err := createIndexes(); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
@@ -79,15 +115,203 @@ func createIndexes() error {
 		return err
 	}
 
+	// Quiz versions are unique per book (failed records use version 0)
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_quizzes_book_version ON quizzes(book_id, version) WHERE version > 0").Error; err != nil {
+		return err
+	}
+
 	// Index for quiz status
 	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_books_quiz_status ON books(quiz_status)").Error; err != nil {
 		return err
 	}
 
+	// Index for claiming quiz jobs
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_quiz_jobs_claim ON quiz_jobs(status, run_after)").Error; err != nil {
+		return err
+	}
+
+	// At most one active (queued or running) job per book, difficulty and
+	// language; replaces the indexes used before difficulty levels and
+	// quiz languages
+	for _, legacy := range []string{"idx_quiz_jobs_active_book", "idx_quiz_jobs_active_book_difficulty"} {
+		if err := DB.Exec("DROP INDEX IF EXISTS " + legacy).Error; err != nil {
+			return err
+		}
+	}
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_quiz_jobs_active_variant ON quiz_jobs(book_id, difficulty, language) WHERE status IN ('queued', 'running')").Error; err != nil {
+		return err
+	}
+
 	log.Println("✅ Database indexes created")
 	return nil
 }
 
+// normalizeBookISBNs rewrites the isbn and isbn13 columns of every book to
+// the form isbn.Canonical stores and then creates the unique isbn13 index.
+// The rewrite runs once and is recorded in schema_migrations; books saved
+// later are stored in canonical form. Books that turn out to be the same
+// book are logged and left unchanged for an admin to merge; the index is
+// created on the first startup after none are left.
+func normalizeBookISBNs() error {
+	const migration = "normalize_book_isbns"
+	applied, err := migrationApplied(migration)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		if err := rewriteBookISBNs(); err != nil {
+			return err
+		}
+		if err := markMigrationApplied(migration); err != nil {
+			return err
+		}
+	}
+	return createBookISBN13Index()
+}
+
+// rewriteBookISBNs stores the canonical ISBNs of every book that has a
+// single row for its ISBN
+func rewriteBookISBNs() error {
+	var rows []struct {
+		ID     string
+		ISBN   string
+		ISBN13 string
+	}
+	if err := DB.Raw("SELECT id, isbn, isbn13 FROM books ORDER BY created_at").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	// Group books by their stored ISBN-13 (or isbn for invalid values)
+	groups := make(map[string][]int)
+	stored := make([][2]string, len(rows))
+	for i, row := range rows {
+		isbnValue, isbn13Value := isbn.Canonical(row.ISBN, row.ISBN13)
+		stored[i] = [2]string{isbnValue, isbn13Value}
+		key := isbn13Value
+		if key == "" {
+			key = isbnValue
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	normalized := 0
+	for key, group := range groups {
+		if len(group) > 1 {
+			ids := make([]string, len(group))
+			for j, i := range group {
+				ids[j] = rows[i].ID
+			}
+			log.Printf("⚠️ Books %s share ISBN %s; merge them to create the unique ISBN index", strings.Join(ids, ", "), key)
+			continue
+		}
+
+		i := group[0]
+		if rows[i].ISBN == stored[i][0] && rows[i].ISBN13 == stored[i][1] {
+			continue
+		}
+		if err := DB.Exec("UPDATE books SET isbn = ?, isbn13 = ? WHERE id = ?", stored[i][0], stored[i][1], rows[i].ID).Error; err != nil {
+			log.Printf("⚠️ Failed to normalize ISBN of book %s: %v", rows[i].ID, err)
+			continue
+		}
+		normalized++
+	}
+	if normalized > 0 {
+		log.Printf("✅ Normalized the ISBNs of %d books", normalized)
+	}
+	return nil
+}
+
+// createBookISBN13Index creates the unique isbn13 index unless it exists or
+// books still share an ISBN-13
+func createBookISBN13Index() error {
+	var exists bool
+	if err := DB.Raw("SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = 'books' AND indexname = 'idx_books_isbn13')").Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	var duplicates int64
+	if err := DB.Raw("SELECT COUNT(*) FROM (SELECT isbn13 FROM books WHERE isbn13 <> '' GROUP BY isbn13 HAVING COUNT(*) > 1) AS d").Scan(&duplicates).Error; err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		log.Printf("⚠️ Skipping unique index idx_books_isbn13: %d duplicate ISBNs", duplicates)
+		return nil
+	}
+	return DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_books_isbn13 ON books(isbn13) WHERE isbn13 <> ''").Error
+}
+
+// migrationApplied reports whether a one-time data migration has run
+func migrationApplied(name string) (bool, error) {
+	if err := DB.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (name text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())").Error; err != nil {
+		return false, err
+	}
+	var count int64
+	if err := DB.Raw("SELECT COUNT(*) FROM schema_migrations WHERE name = ?", name).Scan(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// markMigrationApplied records a one-time data migration as done
+func markMigrationApplied(name string) error {
+	return DB.Exec("INSERT INTO schema_migrations (name) VALUES (?) ON CONFLICT (name) DO NOTHING", name).Error
+}
+
+// migrateBookSearch maintains books.search_vector, a weighted tsvector of
+// title (A), authors (B), categories (C) and description (D). It is kept up
+// to date by a trigger so every write path is covered. The "simple"
+// configuration is used because books are in many languages.
+func migrateBookSearch() error {
+	statements := []string{
+		"ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		`CREATE OR REPLACE FUNCTION books_search_vector(title text, authors text[], categories text[], description text)
+		RETURNS tsvector LANGUAGE sql IMMUTABLE AS $$
+			SELECT setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(array_to_string(authors, ' '), '')), 'B') ||
+				setweight(to_tsvector('simple', coalesce(array_to_string(categories, ' '), '')), 'C') ||
+				setweight(to_tsvector('simple', coalesce(description, '')), 'D')
+		$$`,
+		`CREATE OR REPLACE FUNCTION books_search_vector_update() RETURNS trigger LANGUAGE plpgsql AS $$
+		BEGIN
+			NEW.search_vector := books_search_vector(NEW.title, NEW.authors, NEW.categories, NEW.description);
+			RETURN NEW;
+		END
+		$$`,
+		"DROP TRIGGER IF EXISTS books_search_vector_trigger ON books",
+		`CREATE TRIGGER books_search_vector_trigger BEFORE INSERT OR UPDATE OF title, authors, categories, description
+		ON books FOR EACH ROW EXECUTE FUNCTION books_search_vector_update()`,
+		"UPDATE books SET search_vector = books_search_vector(title, authors, categories, description) WHERE search_vector IS NULL",
+		"CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector)",
+	}
+
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropLegacyQuizBookIndex drops the unique book_id index from before quiz
+// versioning, which allowed only one quiz per book. AutoMigrate recreates
+// it as a regular index.
+func dropLegacyQuizBookIndex() error {
+	var unique bool
+	err := DB.Raw(`SELECT EXISTS (
+		SELECT 1 FROM pg_indexes
+		WHERE tablename = 'quizzes' AND indexname = 'idx_quizzes_book_id' AND indexdef LIKE 'CREATE UNIQUE%'
+	)`).Scan(&unique).Error
+	if err != nil || !unique {
+		return err
+	}
+
+	log.Println("Dropping legacy unique index idx_quizzes_book_id...")
+	return DB.Exec("DROP INDEX IF EXISTS idx_quizzes_book_id").Error
+}
+
 // CloseDatabase closes the database connection
 func CloseDatabase() error {
 	sqlDB, err := DB.DB()
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is the shared Redis client. It is nil unless a feature configured
+// with a Redis backend called InitRedis.
+var Redis *redis.Client
+
+// InitRedis connects to Redis once; later calls reuse the connection
+func InitRedis(cfg *config.Config) error {
+	if Redis != nil {
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to connect to redis at %s: %w", cfg.Redis.Addr(), err)
+	}
+
+	Redis = client
+	log.Println("✅ Redis connection established")
+	return nil
+}
+
+// CloseRedis closes the Redis connection if one was opened
+func CloseRedis() {
+	if Redis != nil {
+		Redis.Close()
+	}
+}
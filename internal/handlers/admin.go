@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/bookwise/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminHandler handles admin-only endpoints
+type AdminHandler struct {
+	quizWorker *services.QuizWorker
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(quizWorker *services.QuizWorker) *AdminHandler {
+	return &AdminHandler{
+		quizWorker: quizWorker,
+	}
+}
+
+// UpdateRoleRequest represents the request body for changing a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// RetryFailedQuizzes enqueues quiz generation for every failed book
+// POST /admin/quizzes/retry-failed
+func (h *AdminHandler) RetryFailedQuizzes(c *gin.Context) {
+	count, err := h.quizWorker.RetryFailedQuizzes()
+	if err != nil {
+		respondAdminError(c, "Başarısız quizler yeniden denenemedi", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    gin.H{"enqueued": count},
+		"message": fmt.Sprintf("%d başarısız quiz yeniden kuyruğa eklendi", count),
+	})
+}
+
+// ProcessPendingQuizzes enqueues quiz generation for pending and failed books
+// POST /admin/quizzes/process-pending
+func (h *AdminHandler) ProcessPendingQuizzes(c *gin.Context) {
+	count, err := h.quizWorker.ProcessPendingQuizzes()
+	if err != nil {
+		respondAdminError(c, "Bekleyen quizler işlenemedi", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    gin.H{"enqueued": count},
+		"message": fmt.Sprintf("%d bekleyen quiz kuyruğa eklendi", count),
+	})
+}
+
+// ResetFailedQuizzes puts failed books back to pending without enqueueing
+// POST /admin/quizzes/reset-failed
+func (h *AdminHandler) ResetFailedQuizzes(c *gin.Context) {
+	count, err := h.quizWorker.ResetFailedQuizzes()
+	if err != nil {
+		respondAdminError(c, "Başarısız quizler sıfırlanamadı", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"reset": count},
+		"message": fmt.Sprintf("%d başarısız quiz sıfırlandı", count),
+	})
+}
+
+// GetWorker returns quiz worker statistics
+// GET /admin/worker
+func (h *AdminHandler) GetWorker(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.quizWorker.GetStats(),
+	})
+}
+
+// StartWorker starts the quiz worker pool of this replica
+// POST /admin/worker/start
+func (h *AdminHandler) StartWorker(c *gin.Context) {
+	if err := h.quizWorker.Start(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Quiz işçileri hâlâ durduruluyor, çalışan işler bitince tekrar deneyin",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.quizWorker.GetStats(),
+		"message": "Quiz işçileri başlatıldı",
+	})
+}
+
+// StopWorker stops the quiz worker pool of this replica. Running jobs are
+// finished in the background; queued jobs stay queued.
+// POST /admin/worker/stop
+func (h *AdminHandler) StopWorker(c *gin.Context) {
+	h.quizWorker.BeginStop()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    h.quizWorker.GetStats(),
+		"message": "Quiz işçileri durduruluyor; çalışan işler bitince duracak",
+	})
+}
+
+// ListUsers lists users, newest first
+// GET /admin/users?role={role}
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	query := database.DB.Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		if !models.ValidRole(role) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "role must be one of: reader, editor, admin",
+			})
+			return
+		}
+		query = query.Where("role = ?", role)
+	}
+
+	var users []models.User
+	if err := query.Order("created_at DESC").Find(&users).Error; err != nil {
+		respondAdminError(c, "Kullanıcılar listelenemedi", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    users,
+	})
+}
+
+// UpdateUserRole changes a user's role
+// PATCH /admin/users/:id/role
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kullanıcı ID",
+		})
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "role must be one of: reader, editor, admin",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "Kullanıcı bulunamadı",
+			})
+			return
+		}
+		respondAdminError(c, "Kullanıcı yüklenemedi", err)
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		respondAdminError(c, "Kullanıcı rolü güncellenemedi", err)
+		return
+	}
+
+	log.Printf("👤 User %s role changed to %s", user.ID, req.Role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    user,
+	})
+}
+
+// respondAdminError logs err and responds with 500
+func respondAdminError(c *gin.Context, message string, err error) {
+	log.Printf("❌ %s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"error":   message,
+		"details": err.Error(),
+	})
+}
@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/isbn"
+	"github.com/bookwise/api/internal/middleware"
 	"github.com/bookwise/api/internal/models"
 	"github.com/bookwise/api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // BooksHandler handles book-related endpoints
@@ -27,7 +37,7 @@ func NewBooksHandler(bookMerger *services.BookMergerService, quizWorker *service
 }
 
 // SearchBook handles book search requests - returns list of books
-// GET /books/search?q={query}&type={isbn|title|author}&limit={limit}
+// GET /books/search?q={query}&type={isbn|title|author}&limit={limit}&source={local|external|both}
 func (h *BooksHandler) SearchBook(c *gin.Context) {
 	query := c.Query("q")
 	searchType := c.Query("type")
@@ -54,6 +64,20 @@ func (h *BooksHandler) SearchBook(c *gin.Context) {
 		return
 	}
 
+	// ISBN searches must be valid ISBN-10/13; normalize so every provider sees the same value
+	if searchType == "isbn" {
+		parsed, err := isbn.Parse(query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Geçersiz ISBN",
+				"details": err.Error(),
+			})
+			return
+		}
+		query = parsed.ISBN13
+	}
+
 	// Parse limit
 	limit := 10
 	if limitStr != "" {
@@ -68,58 +92,87 @@ func (h *BooksHandler) SearchBook(c *gin.Context) {
 		limit = 40
 	}
 
-	log.Printf("🔍 Book search request: query='%s', type='%s', limit=%d", query, searchType, limit)
+	source := c.DefaultQuery("source", "external")
+	if source != "local" && source != "external" && source != "both" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "source must be one of: local, external, both",
+		})
+		return
+	}
+
+	log.Printf("🔍 Book search request: query='%s', type='%s', limit=%d, source=%s", query, searchType, limit, source)
 
-	// Search from external sources
-	books, err := h.bookMerger.SearchBooks(query, searchType, limit)
-	if err != nil {
-		log.Printf("❌ Book search failed: %v", err)
+	results := make([]bookSearchResult, 0, limit)
+	seenISBNs := make(map[string]bool)
+	addResult := func(result bookSearchResult) {
+		if (result.ISBN != "" && seenISBNs[result.ISBN]) || (result.ISBN13 != "" && seenISBNs[result.ISBN13]) {
+			return
+		}
+		results = append(results, result)
+		if result.ISBN != "" {
+			seenISBNs[result.ISBN] = true
+		}
+		if result.ISBN13 != "" {
+			seenISBNs[result.ISBN13] = true
+		}
+	}
+
+	// Stored books come first; good local matches make external calls unnecessary
+	searchExternal := source != "local"
+	if source != "external" {
+		hits, err := services.SearchLocalBooks(query, searchType, services.BookFilter{}, limit, 0)
+		if err != nil {
+			log.Printf("❌ Local book search failed: %v", err)
+			if !searchExternal {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"error":   "Kitap araması başarısız",
+					"details": err.Error(),
+				})
+				return
+			}
+		}
+		for i := range hits {
+			addResult(localBookSearchResult(&hits[i]))
+		}
+		if searchExternal && h.bookMerger.LocalHitsSufficient(hits, limit) {
+			log.Printf("✅ %d local matches are sufficient, skipping external providers", len(hits))
+			searchExternal = false
+		}
+	}
+
+	if searchExternal {
+		books, cacheHit, err := h.bookMerger.SearchBooksCached(c.Request.Context(), query, searchType, limit)
+		c.Header("X-Cache", cacheStatus(cacheHit))
+		if err != nil && len(results) == 0 {
+			log.Printf("❌ Book search failed: %v", err)
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "Kitap bulunamadı",
+				"details": err.Error(),
+			})
+			return
+		}
+		for _, book := range books {
+			addResult(externalBookSearchResult(book))
+		}
+	}
+
+	if len(results) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "Kitap bulunamadı",
-			"details": err.Error(),
+			"details": "no stored books match the query",
 		})
 		return
 	}
-
-	log.Printf("✅ Found %d books", len(books))
-
-	// Convert to response format
-	type BookSearchResult struct {
-		Title         string   `json:"title"`
-		Authors       []string `json:"authors"`
-		ISBN          string   `json:"isbn,omitempty"`
-		ISBN13        string   `json:"isbn13,omitempty"`
-		Description   string   `json:"description,omitempty"`
-		Publisher     string   `json:"publisher,omitempty"`
-		PublishedDate string   `json:"published_date,omitempty"`
-		PageCount     int      `json:"page_count,omitempty"`
-		Categories    []string `json:"categories,omitempty"`
-		Language      string   `json:"language,omitempty"`
-		CoverURL      string   `json:"cover_url,omitempty"`
-		ThumbnailURL  string   `json:"thumbnail_url,omitempty"`
-		Source        string   `json:"source"`
-	}
-
-	results := make([]BookSearchResult, len(books))
-	for i, book := range books {
-		results[i] = BookSearchResult{
-			Title:         book.Title,
-			Authors:       book.Authors,
-			ISBN:          book.ISBN,
-			ISBN13:        book.ISBN13,
-			Description:   book.Description,
-			Publisher:     book.Publisher,
-			PublishedDate: book.PublishedDate,
-			PageCount:     book.PageCount,
-			Categories:    book.Categories,
-			Language:      book.Language,
-			CoverURL:      book.CoverURL,
-			ThumbnailURL:  book.ThumbnailURL,
-			Source:        book.Source,
-		}
+	if len(results) > limit {
+		results = results[:limit]
 	}
 
+	log.Printf("✅ Found %d books", len(results))
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    results,
@@ -128,8 +181,73 @@ func (h *BooksHandler) SearchBook(c *gin.Context) {
 	})
 }
 
+// bookSearchResult is a book search result. Stored books have an ID, a
+// rank and highlights, and "local" as source.
+type bookSearchResult struct {
+	ID            *uuid.UUID        `json:"id,omitempty"`
+	Title         string            `json:"title"`
+	Authors       []string          `json:"authors"`
+	ISBN          string            `json:"isbn,omitempty"`
+	ISBN13        string            `json:"isbn13,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Publisher     string            `json:"publisher,omitempty"`
+	PublishedDate string            `json:"published_date,omitempty"`
+	PageCount     int               `json:"page_count,omitempty"`
+	Categories    []string          `json:"categories,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	CoverURL      string            `json:"cover_url,omitempty"`
+	ThumbnailURL  string            `json:"thumbnail_url,omitempty"`
+	Source        string            `json:"source"`
+	QuizStatus    string            `json:"quiz_status,omitempty"`
+	Rank          float64           `json:"rank,omitempty"`
+	Highlights    map[string]string `json:"highlights,omitempty"`
+}
+
+// externalBookSearchResult converts a provider result
+func externalBookSearchResult(book *services.BookData) bookSearchResult {
+	return bookSearchResult{
+		Title:         book.Title,
+		Authors:       book.Authors,
+		ISBN:          book.ISBN,
+		ISBN13:        book.ISBN13,
+		Description:   book.Description,
+		Publisher:     book.Publisher,
+		PublishedDate: book.PublishedDate,
+		PageCount:     book.PageCount,
+		Categories:    book.Categories,
+		Language:      book.Language,
+		CoverURL:      book.CoverURL,
+		ThumbnailURL:  book.ThumbnailURL,
+		Source:        book.Source,
+	}
+}
+
+// localBookSearchResult converts a stored book match
+func localBookSearchResult(hit *services.LocalSearchHit) bookSearchResult {
+	id := hit.ID
+	return bookSearchResult{
+		ID:            &id,
+		Title:         hit.Title,
+		Authors:       hit.Authors,
+		ISBN:          hit.ISBN,
+		ISBN13:        hit.ISBN13,
+		Description:   hit.Description,
+		Publisher:     hit.Publisher,
+		PublishedDate: hit.PublishedDate,
+		PageCount:     hit.PageCount,
+		Categories:    hit.Categories,
+		Language:      hit.Language,
+		CoverURL:      hit.CoverURL,
+		ThumbnailURL:  hit.ThumbnailURL,
+		Source:        "local",
+		QuizStatus:    hit.QuizStatus,
+		Rank:          hit.Rank,
+		Highlights:    hit.Highlights(),
+	}
+}
+
 // GetBookByID handles get book by UUID
-// GET /books/:id
+// GET /books/:id?include=provenance
 func (h *BooksHandler) GetBookByID(c *gin.Context) {
 	idStr := c.Param("id")
 	
@@ -151,23 +269,252 @@ func (h *BooksHandler) GetBookByID(c *gin.Context) {
 		return
 	}
 
+	response := book.ToResponse()
+	if hasInclude(c, "provenance") {
+		response.Provenance = book.GetProvenance()
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    book.ToResponse(),
+		"data":    response,
 	})
 }
 
+// bookFieldsRequest holds editable book fields sent by clients. Omitted
+// fields are nil.
+type bookFieldsRequest struct {
+	Title         *string   `json:"title"`
+	Authors       *[]string `json:"authors"`
+	Description   *string   `json:"description"`
+	Publisher     *string   `json:"publisher"`
+	PublishedDate *string   `json:"published_date"`
+	PageCount     *int      `json:"page_count"`
+	Categories    *[]string `json:"categories"`
+	Language      *string   `json:"language"`
+	CoverURL      *string   `json:"cover_url"`
+	ThumbnailURL  *string   `json:"thumbnail_url"`
+}
+
+// values returns the sent fields as a book and the names of the fields sent
+func (r *bookFieldsRequest) values() (models.Book, []string) {
+	var book models.Book
+	fields := []string{}
+	setString := func(field string, value *string, target *string) {
+		if value != nil {
+			*target = *value
+			fields = append(fields, field)
+		}
+	}
+	setList := func(field string, value *[]string, target *pq.StringArray) {
+		if value != nil {
+			*target = pq.StringArray(*value)
+			fields = append(fields, field)
+		}
+	}
+
+	setString(models.FieldTitle, r.Title, &book.Title)
+	setList(models.FieldAuthors, r.Authors, &book.Authors)
+	setString(models.FieldDescription, r.Description, &book.Description)
+	setString(models.FieldPublisher, r.Publisher, &book.Publisher)
+	setString(models.FieldPublishedDate, r.PublishedDate, &book.PublishedDate)
+	if r.PageCount != nil {
+		book.PageCount = *r.PageCount
+		fields = append(fields, models.FieldPageCount)
+	}
+	setList(models.FieldCategories, r.Categories, &book.Categories)
+	setString(models.FieldLanguage, r.Language, &book.Language)
+	setString(models.FieldCoverURL, r.CoverURL, &book.CoverURL)
+	setString(models.FieldThumbnailURL, r.ThumbnailURL, &book.ThumbnailURL)
+	return book, fields
+}
+
+// bookEditRequest is the body of PATCH /books/:id. Omitted fields are
+// left unchanged.
+type bookEditRequest struct {
+	bookFieldsRequest
+	LockFields   []string `json:"lock_fields"`
+	UnlockFields []string `json:"unlock_fields"`
+}
+
+// toEdit converts the request to a service edit
+func (r *bookEditRequest) toEdit() services.BookEdit {
+	values, fields := r.values()
+	return services.BookEdit{Values: values, Fields: fields, Lock: r.LockFields, Unlock: r.UnlockFields}
+}
+
+// UpdateBook handles manual corrections of a book. Edited fields are
+// locked so refreshes from providers keep them.
+// PATCH /books/:id
+func (h *BooksHandler) UpdateBook(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap ID",
+		})
+		return
+	}
+
+	var req bookEditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz istek",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	edit := req.toEdit()
+	if len(edit.Fields) == 0 && len(edit.Lock) == 0 && len(edit.Unlock) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Güncellenecek alan yok",
+			"details": fmt.Sprintf("editable fields: %s", strings.Join(models.EditableBookFields, ", ")),
+		})
+		return
+	}
+
+	book, err := services.UpdateBook(bookID, edit)
+	if err != nil {
+		respondBookError(c, err, "Kitap güncellenemedi")
+		return
+	}
+
+	response := book.ToResponse()
+	response.Provenance = book.GetProvenance()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+		"message": "Kitap güncellendi",
+	})
+}
+
+// DeleteBook handles deleting a book with its quizzes, attempts and jobs
+// DELETE /books/:id
+func (h *BooksHandler) DeleteBook(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap ID",
+		})
+		return
+	}
+
+	deletion, err := services.DeleteBook(bookID)
+	if err != nil {
+		respondBookError(c, err, "Kitap silinemedi")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"deleted": deletion},
+		"message": "Kitap silindi",
+	})
+}
+
+// RefreshBook handles re-fetching a book's metadata from the providers.
+// Without apply=true only the differences are returned.
+// POST /books/:id/refresh?apply=true
+func (h *BooksHandler) RefreshBook(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap ID",
+		})
+		return
+	}
+
+	apply := c.Query("apply") == "true"
+
+	refresh, err := h.bookMerger.RefreshBook(c.Request.Context(), bookID, apply)
+	if err != nil {
+		respondBookError(c, err, "Kitap bilgileri yenilenemedi")
+		return
+	}
+
+	message := fmt.Sprintf("%d alan farklı", len(refresh.Changes))
+	if refresh.Applied {
+		message = "Kitap bilgileri yenilendi"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"book":    refresh.Book.ToResponse(),
+			"changes": refresh.Changes,
+			"applied": refresh.Applied,
+		},
+		"message": message,
+	})
+}
+
+// respondBookError maps book editing errors to HTTP responses
+func respondBookError(c *gin.Context, err error, message string) {
+	var validationErr *services.BookValidationError
+	switch {
+	case errors.Is(err, services.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Kitap bulunamadı",
+		})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap bilgisi",
+			"details": validationErr.Issues,
+		})
+	case errors.Is(err, services.ErrInvalidBookField):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz alan",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrBookNotInSources):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Kitap kaynaklarda bulunamadı",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrBookNotRefreshable):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Kitap yenilenemez",
+			"details": err.Error(),
+		})
+	default:
+		log.Printf("❌ %s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
+
 // GetBookByISBN handles get book by ISBN
 // GET /books/isbn/:isbn
 func (h *BooksHandler) GetBookByISBN(c *gin.Context) {
-	isbn := c.Param("isbn")
+	parsed, err := isbn.Parse(c.Param("isbn"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz ISBN",
+			"details": err.Error(),
+		})
+		return
+	}
 
 	var book models.Book
-	if err := database.DB.Where("isbn = ? OR isbn13 = ?", isbn, isbn).First(&book).Error; err != nil {
+	if err := findBookByISBN(parsed, &book); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "Kitap bulunamadı",
-			"message": "Bu ISBN ile kayıtlı kitap bulunamadı. /books/search?q=" + isbn + "&type=isbn ile arama yapabilirsiniz.",
+			"message": "Bu ISBN ile kayıtlı kitap bulunamadı. /books/search?q=" + parsed.ISBN13 + "&type=isbn ile arama yapabilirsiniz.",
 		})
 		return
 	}
@@ -178,13 +525,16 @@ func (h *BooksHandler) GetBookByISBN(c *gin.Context) {
 	})
 }
 
-// SaveBook saves a book to the database
+// SaveBook saves a book to the database. Metadata is fetched from the
+// providers, or taken from the body for manual entries (editors only).
 // POST /books
-// Body: { "isbn": "...", "generate_quiz": true/false }
+// Body: { "isbn": "...", "generate_quiz": true/false, "data_sources": ["manual"], "title": "...", ... }
 func (h *BooksHandler) SaveBook(c *gin.Context) {
 	type SaveBookRequest struct {
-		ISBN         string `json:"isbn" binding:"required"`
-		GenerateQuiz bool   `json:"generate_quiz"`
+		ISBN         string   `json:"isbn" binding:"required"`
+		GenerateQuiz bool     `json:"generate_quiz"`
+		DataSources  []string `json:"data_sources"`
+		bookFieldsRequest
 	}
 
 	var req SaveBookRequest
@@ -197,20 +547,61 @@ func (h *BooksHandler) SaveBook(c *gin.Context) {
 		return
 	}
 
-	log.Printf("💾 Save book request: ISBN='%s', generate_quiz=%v", req.ISBN, req.GenerateQuiz)
+	parsed, err := isbn.Parse(req.ISBN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz ISBN",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Sending metadata (or data_sources ["manual"]) creates a manual entry
+	values, fields := req.values()
+	manual := len(fields) > 0 || len(req.DataSources) > 0
+	if manual {
+		if len(req.DataSources) > 0 && !(len(req.DataSources) == 1 && req.DataSources[0] == models.DataSourceManual) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Geçersiz veri kaynağı",
+				"details": `data_sources must be ["manual"] for manual entries`,
+			})
+			return
+		}
+		if !middleware.HasRole(c, models.RoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Manuel kitap eklemek için editör yetkisi gerekli",
+			})
+			return
+		}
+	}
+
+	log.Printf("💾 Save book request: ISBN='%s', generate_quiz=%v, manual=%v", parsed.ISBN13, req.GenerateQuiz, manual)
 
 	// First check if book already exists in database
 	var existingBook models.Book
-	err := database.DB.Where("isbn = ? OR isbn13 = ?", req.ISBN, req.ISBN).First(&existingBook).Error
+	err = findBookByISBN(parsed, &existingBook)
 	if err == nil {
 		log.Printf("ℹ️ Book already exists in database: %s (ISBN: %s)", existingBook.Title, existingBook.ISBN)
 		
 		// If quiz generation requested and not already generated
 		if req.GenerateQuiz && existingBook.QuizStatus == "pending" {
-			h.quizWorker.Enqueue(existingBook.ID)
+			job, err := h.quizWorker.Enqueue(existingBook.ID, services.QuizJobOptions{})
+			if err != nil {
+				log.Printf("❌ Failed to enqueue quiz generation: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"error":   "Quiz oluşturma kuyruğa eklenemedi",
+					"details": err.Error(),
+				})
+				return
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"success": true,
 				"data":    existingBook.ToResponse(),
+				"job_id":  job.ID,
 				"message": "Kitap zaten kayıtlı. Quiz oluşturuluyor...",
 			})
 			return
@@ -224,16 +615,29 @@ func (h *BooksHandler) SaveBook(c *gin.Context) {
 		return
 	}
 
-	// Fetch book details from external sources
-	book, err := h.bookMerger.SearchBook(req.ISBN, "isbn")
-	if err != nil {
-		log.Printf("❌ Book not found: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "Kitap bulunamadı",
-			"details": err.Error(),
-		})
-		return
+	var book *models.Book
+	if manual {
+		book, err = services.NewManualBook(parsed, values, fields)
+		if err != nil {
+			respondBookError(c, err, "Kitap kaydedilemedi")
+			return
+		}
+	} else {
+		// Fetch book details from external sources
+		book, err = h.bookMerger.SearchBook(c.Request.Context(), parsed.ISBN13, "isbn")
+		if err != nil {
+			log.Printf("❌ Book not found: %v", err)
+			response := gin.H{
+				"success": false,
+				"error":   "Kitap bulunamadı",
+				"details": err.Error(),
+			}
+			if errors.Is(err, services.ErrBookNotInSources) {
+				response["hint"] = "Kitap bilgilerini (title, authors, ...) ve \"data_sources\": [\"manual\"] göndererek manuel ekleyebilirsiniz"
+			}
+			c.JSON(http.StatusNotFound, response)
+			return
+		}
 	}
 
 	// Save to database
@@ -251,20 +655,139 @@ func (h *BooksHandler) SaveBook(c *gin.Context) {
 
 	// Trigger quiz generation if requested
 	message := "Kitap başarıyla kaydedildi"
+	response := gin.H{
+		"success": true,
+		"data":    book.ToResponse(),
+	}
 	if req.GenerateQuiz {
-		h.quizWorker.Enqueue(book.ID)
-		message = "Kitap başarıyla kaydedildi. Quiz oluşturuluyor..."
+		if job, err := h.quizWorker.Enqueue(book.ID, services.QuizJobOptions{}); err != nil {
+			// The book is saved; the quiz can still be requested via /generate-quiz
+			log.Printf("❌ Failed to enqueue quiz generation: %v", err)
+			message = "Kitap başarıyla kaydedildi ancak quiz oluşturma kuyruğa eklenemedi"
+		} else {
+			response["job_id"] = job.ID
+			message = "Kitap başarıyla kaydedildi. Quiz oluşturuluyor..."
+		}
 	}
+	response["message"] = message
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// maxBulkImportBody bounds the size of a bulk import request body
+const maxBulkImportBody = 5 << 20
+
+// BulkImportBooks saves many books by ISBN at once and reports the outcome
+// of each ISBN. Quizzes are enqueued for new books with generate_quiz.
+// POST /books/bulk
+// Body: ["isbn", ...], { "isbns": [...], "generate_quiz": true/false }, or
+// a CSV upload (text/csv body or multipart "file") with ?generate_quiz=true
+func (h *BooksHandler) BulkImportBooks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkImportBody)
 
-	c.JSON(http.StatusCreated, gin.H{
+	isbns, generateQuiz, err := parseBulkImportRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz istek",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(isbns) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "En az bir ISBN gerekli",
+		})
+		return
+	}
+	if maxBatch := h.bookMerger.ImportMaxBatch(); maxBatch > 0 && len(isbns) > maxBatch {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Çok fazla ISBN",
+			"details": fmt.Sprintf("at most %d ISBNs per request, use the bookwise import command for larger lists", maxBatch),
+		})
+		return
+	}
+
+	log.Printf("📥 Bulk import request: %d ISBNs, generate_quiz=%v", len(isbns), generateQuiz)
+
+	opts := services.BookImportOptions{}
+	if generateQuiz {
+		opts.EnqueueQuiz = func(bookID uuid.UUID) (*models.QuizJob, error) {
+			return h.quizWorker.Enqueue(bookID, services.QuizJobOptions{})
+		}
+	}
+	results := h.bookMerger.ImportISBNs(c.Request.Context(), isbns, opts, nil)
+
+	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    book.ToResponse(),
-		"message": message,
+		"data":    results,
+		"summary": services.SummarizeImport(results),
+		"message": fmt.Sprintf("%d ISBN işlendi", len(results)),
 	})
 }
 
-// GenerateQuiz generates quiz for a specific book
-// POST /books/:id/generate-quiz
+// parseBulkImportRequest reads the ISBN list of a bulk import request
+func parseBulkImportRequest(c *gin.Context) ([]string, bool, error) {
+	generateQuiz := c.Query("generate_quiz") == "true"
+
+	switch c.ContentType() {
+	case "multipart/form-data":
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, false, fmt.Errorf("CSV file is required: %w", err)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, false, err
+		}
+		defer file.Close()
+
+		isbns, err := services.ParseISBNList(file)
+		return isbns, generateQuiz || c.PostForm("generate_quiz") == "true", err
+	case "text/csv", "text/plain":
+		isbns, err := services.ParseISBNList(c.Request.Body)
+		return isbns, generateQuiz, err
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A bare JSON array of ISBNs
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var isbns []string
+		if err := json.Unmarshal(trimmed, &isbns); err != nil {
+			return nil, false, err
+		}
+		return isbns, generateQuiz, nil
+	}
+
+	var req struct {
+		ISBNs        []string `json:"isbns"`
+		GenerateQuiz bool     `json:"generate_quiz"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false, err
+	}
+	return req.ISBNs, generateQuiz || req.GenerateQuiz, nil
+}
+
+// GenerateQuizRequest is the optional body of a quiz generation request
+type GenerateQuizRequest struct {
+	Difficulty     string   `json:"difficulty"`      // "easy", "medium" (default) or "hard"
+	Language       string   `json:"language"`        // "en" or "tr"; default Accept-Language, then the book's language
+	QuestionsCount int      `json:"questions_count"` // 1-20, default QUIZ_QUESTIONS_COUNT
+	AgeGroup       string   `json:"age_group"`       // "children", "teen" or "adult"
+	Focus          []string `json:"focus"`           // "plot", "characters", "themes", "author"
+}
+
+// GenerateQuiz generates quiz for a specific book. Quizzes of different
+// difficulties coexist; one with the same parameters is only regenerated
+// with force=true.
+// POST /books/:id/generate-quiz?force=true
 func (h *BooksHandler) GenerateQuiz(c *gin.Context) {
 	idStr := c.Param("id")
 	
@@ -287,39 +810,101 @@ func (h *BooksHandler) GenerateQuiz(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🎯 Generate quiz request for book: %s (ID: %s, Status: %s)", book.Title, book.ID, book.QuizStatus)
+	// Regenerating an existing quiz is reserved for editors
+	force := c.Query("force") == "true"
+	if force && !middleware.HasRole(c, models.RoleEditor) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "Quiz yeniden oluşturmak için editör yetkisi gerekli",
+		})
+		return
+	}
 
-	// Check quiz status
-	if book.QuizStatus == "completed" {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "Quiz zaten oluşturulmuş. Yeni quiz oluşturulsun mu?",
-			"status":  "completed",
+	// The body is optional; without one a medium quiz is generated
+	var req GenerateQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz istek",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	if book.QuizStatus == "generating" {
-		c.JSON(http.StatusAccepted, gin.H{
+	params, err := services.NormalizeQuizParams(models.QuizParams{
+		Difficulty:     req.Difficulty,
+		Language:       req.Language,
+		QuestionsCount: req.QuestionsCount,
+		AgeGroup:       req.AgeGroup,
+		Focus:          req.Focus,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Quiz şu anda oluşturuluyor. Lütfen bekleyin.",
-			"status":  "generating",
+			"error":   "Geçersiz quiz parametreleri",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if params.Language == "" {
+		params.Language = services.ResolveQuizLanguage(append(acceptedLanguages(c.GetHeader("Accept-Language")), book.Language)...)
+	}
+
+	log.Printf("🎯 Generate quiz request for book: %s (ID: %s, Difficulty: %s, Language: %s, Force: %v)", book.Title, book.ID, params.Difficulty, params.Language, force)
+
+	// A quiz with the same parameters exists; force=true generates a new version next to it
+	if !force {
+		if quiz, err := h.quizWorker.FindQuiz(book.ID, params); err == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success":    true,
+				"message":    "Quiz zaten oluşturulmuş. Yeni quiz oluşturulsun mu?",
+				"status":     "completed",
+				"quiz_id":    quiz.ID,
+				"difficulty": quiz.Difficulty,
+				"language":   quiz.Language,
+			})
+			return
+		}
+	}
+
+	if job, err := services.ActiveJob(book.ID, params.Difficulty, params.Language); err == nil && job.Status == models.JobStatusRunning {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success":    false,
+			"message":    "Quiz şu anda oluşturuluyor. Lütfen bekleyin.",
+			"status":     "generating",
+			"job_id":     job.ID,
+			"difficulty": job.Difficulty,
+			"language":   job.Language,
 		})
 		return
 	}
 
 	// Trigger quiz generation
-	h.quizWorker.Enqueue(book.ID)
+	job, err := h.quizWorker.Enqueue(book.ID, services.QuizJobOptions{Force: force, Params: params})
+	if err != nil {
+		log.Printf("❌ Failed to enqueue quiz generation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz oluşturma kuyruğa eklenemedi",
+			"details": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"success": true,
-		"message": "Quiz oluşturma işlemi başlatıldı. Lütfen birkaç saniye sonra kontrol edin.",
-		"status":  "generating",
+		"message":    "Quiz oluşturma işlemi başlatıldı. Lütfen birkaç saniye sonra kontrol edin.",
+		"status":     "generating",
+		"job_id":     job.ID,
+		"difficulty": job.Difficulty,
+		"language":   job.Language,
 	})
 }
 
-// ListBooks handles listing all books with pagination
-// GET /books?page=1&limit=10
+// ListBooks handles listing all books with filters, sorting and offset or
+// cursor pagination, or searching them with q
+// GET /books?page=1&limit=10&sort=created_at&order=desc&author=...&cursor=...&q={query}
 func (h *BooksHandler) ListBooks(c *gin.Context) {
 	page := 1
 	limit := 10
@@ -345,26 +930,199 @@ func (h *BooksHandler) ListBooks(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	var books []models.Book
-	var total int64
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz filtre",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Full-text search over stored books, ranked by relevance. Filters
+	// apply; another order or cursor pagination can't be combined with it.
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		if c.Query("sort") != "" || c.Query("order") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Geçersiz sıralama",
+				"details": "sort and order can't be combined with q: search results are ordered by relevance",
+			})
+			return
+		}
+		if _, ok := c.GetQuery("cursor"); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Geçersiz cursor",
+				"details": "cursor pagination can't be combined with q: use page",
+			})
+			return
+		}
+		h.searchStoredBooks(c, q, filter, page, limit, offset)
+		return
+	}
 
-	database.DB.Model(&models.Book{}).Count(&total)
-	database.DB.Offset(offset).Limit(limit).Order("created_at DESC").Find(&books)
+	sort, err := services.ParseBookSort(c.Query("sort"), c.Query("order"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz sıralama",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Sending "cursor" (empty for the first page) selects cursor pagination
+	cursor, cursorMode := c.GetQuery("cursor")
+
+	result, err := services.ListBooks(filter, sort, cursor, offset, limit)
+	if errors.Is(err, services.ErrInvalidBookCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz cursor",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to list books: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Kitaplar listelenemedi",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	responses := make([]*models.BookResponse, len(books))
-	for i, book := range books {
+	responses := make([]*models.BookResponse, len(result.Books))
+	for i, book := range result.Books {
 		responses[i] = book.ToResponse()
 	}
 
+	pagination := gin.H{
+		"limit":       limit,
+		"has_more":    result.NextCursor != "",
+		"next_cursor": result.NextCursor,
+	}
+
+	// Offset pagination keeps page totals for existing clients; cursor
+	// pagination skips the count
+	if !cursorMode {
+		total, err := services.CountBooks(filter)
+		if err != nil {
+			log.Printf("❌ Failed to count books: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "Kitaplar listelenemedi",
+				"details": err.Error(),
+			})
+			return
+		}
+		pagination["page"] = page
+		pagination["total"] = total
+		pagination["total_pages"] = (total + int64(limit) - 1) / int64(limit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       responses,
+		"pagination": pagination,
+	})
+}
+
+// parseBookFilter reads the ListBooks filter query parameters
+func parseBookFilter(c *gin.Context) (services.BookFilter, error) {
+	filter := services.BookFilter{
+		Author:     strings.TrimSpace(c.Query("author")),
+		Category:   strings.TrimSpace(c.Query("category")),
+		Language:   strings.TrimSpace(c.Query("language")),
+		QuizStatus: c.Query("quiz_status"),
+		Publisher:  strings.TrimSpace(c.Query("publisher")),
+	}
+
+	if filter.QuizStatus != "" && !slices.Contains(services.BookQuizStatuses, filter.QuizStatus) {
+		return filter, fmt.Errorf("quiz_status must be one of: %s", strings.Join(services.BookQuizStatuses, ", "))
+	}
+
+	for name, target := range map[string]*int{"published_from": &filter.PublishedFrom, "published_to": &filter.PublishedTo} {
+		if value := c.Query(name); value != "" {
+			year, err := strconv.Atoi(value)
+			if err != nil || year < 1 {
+				return filter, fmt.Errorf("%s must be a year", name)
+			}
+			*target = year
+		}
+	}
+	if filter.PublishedFrom > 0 && filter.PublishedTo > 0 && filter.PublishedFrom > filter.PublishedTo {
+		return filter, fmt.Errorf("published_from must not be after published_to")
+	}
+
+	if value := c.Query("has_cover"); value != "" {
+		hasCover, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("has_cover must be true or false")
+		}
+		filter.HasCover = &hasCover
+	}
+	return filter, nil
+}
+
+// searchStoredBooks responds with stored books matching q and filter, best
+// first. One extra row is fetched to tell whether another page exists.
+func (h *BooksHandler) searchStoredBooks(c *gin.Context, q string, filter services.BookFilter, page, limit, offset int) {
+	hits, err := services.SearchLocalBooks(q, "", filter, limit+1, offset)
+	if err != nil {
+		log.Printf("❌ Local book search failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Kitap araması başarısız",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+
+	results := make([]bookSearchResult, len(hits))
+	for i := range hits {
+		results[i] = localBookSearchResult(&hits[i])
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    responses,
+		"data":    results,
 		"pagination": gin.H{
-			"page":       page,
-			"limit":      limit,
-			"total":      total,
-			"total_pages": (total + int64(limit) - 1) / int64(limit),
+			"page":     page,
+			"limit":    limit,
+			"has_more": hasMore,
 		},
 	})
 }
 
+// findBookByISBN looks up a stored book by any form of a parsed ISBN
+func findBookByISBN(parsed isbn.ISBN, book *models.Book) error {
+	variants := parsed.Variants()
+	return database.DB.Where("isbn IN ? OR isbn13 IN ?", variants, variants).First(book).Error
+}
+
+// hasInclude reports whether the comma-separated "include" query parameter contains name
+func hasInclude(c *gin.Context, name string) bool {
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(include) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheStatus returns the X-Cache header value
+func cacheStatus(hit bool) string {
+	if hit {
+		return "HIT"
+	}
+	return "MISS"
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bookwise/api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles catalog export endpoints
+type ExportHandler struct{}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler() *ExportHandler {
+	return &ExportHandler{}
+}
+
+// ExportBooks streams the saved books as CSV, JSON Lines or MARCXML. The
+// ListBooks filters narrow the export.
+// GET /export/books?format=csv|jsonl|marcxml&include=quizzes&view=play|full&author=...
+func (h *ExportHandler) ExportBooks(c *gin.Context) {
+	// Exported quizzes follow the quiz endpoints: answers only for editors
+	full, ok := quizView(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz filtre",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	opts := services.BookExportOptions{
+		Format:         c.DefaultQuery("format", services.ExportFormatCSV),
+		Filter:         filter,
+		IncludeQuizzes: hasInclude(c, "quizzes"),
+		QuizAnswers:    full,
+	}
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz dışa aktarma isteği",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", services.ExportContentType(opts.Format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ExportFileName(opts.Format, time.Now())))
+	c.Status(http.StatusOK)
+
+	// The status is sent with the first batch, so a failure can only cut
+	// the stream short
+	count, err := services.ExportBooks(c.Request.Context(), c.Writer, opts)
+	if err != nil && !errors.Is(err, c.Request.Context().Err()) {
+		log.Printf("❌ Book export failed after %d books: %v", count, err)
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/middleware"
+	"github.com/bookwise/api/internal/models"
+	"github.com/bookwise/api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// JobsHandler handles quiz generation job endpoints
+type JobsHandler struct {
+	quizWorker *services.QuizWorker
+}
+
+// NewJobsHandler creates a new jobs handler
+func NewJobsHandler(quizWorker *services.QuizWorker) *JobsHandler {
+	return &JobsHandler{
+		quizWorker: quizWorker,
+	}
+}
+
+// ListJobs handles listing quiz jobs with optional filters
+// GET /jobs?status={status}&book_id={uuid}&page=1&limit=20
+func (h *JobsHandler) ListJobs(c *gin.Context) {
+	page := 1
+	limit := 20
+
+	if p := c.Query("page"); p != "" {
+		if _, err := fmt.Sscanf(p, "%d", &page); err != nil {
+			page = 1
+		}
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if _, err := fmt.Sscanf(l, "%d", &limit); err != nil {
+			limit = 20
+		}
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.DB.Model(&models.QuizJob{})
+
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.JobStatusQueued, models.JobStatusRunning, models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled:
+			query = query.Where("status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "status must be one of: queued, running, completed, failed, cancelled",
+			})
+			return
+		}
+	}
+
+	if bookIDStr := c.Query("book_id"); bookIDStr != "" {
+		bookID, err := uuid.Parse(bookIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Geçersiz kitap ID",
+			})
+			return
+		}
+		query = query.Where("book_id = ?", bookID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("❌ Failed to count quiz jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "İşler listelenemedi",
+		})
+		return
+	}
+
+	var jobs []models.QuizJob
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&jobs).Error; err != nil {
+		log.Printf("❌ Failed to list quiz jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "İşler listelenemedi",
+		})
+		return
+	}
+
+	responses := make([]*models.QuizJobResponse, len(jobs))
+	for i := range jobs {
+		responses[i] = jobResponse(c, &jobs[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    responses,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetJob handles get job by ID, including its attempt history
+// GET /jobs/:id
+func (h *JobsHandler) GetJob(c *gin.Context) {
+	jobID, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.quizWorker.GetJob(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    jobResponse(c, job),
+	})
+}
+
+// jobResponse shows error details to editors only; readers polling their
+// generation job see its status and timing
+func jobResponse(c *gin.Context, job *models.QuizJob) *models.QuizJobResponse {
+	if middleware.HasRole(c, models.RoleEditor) {
+		return job.ToResponse()
+	}
+	return job.ToPublicResponse()
+}
+
+// CancelJob handles cancelling a queued or running job
+// POST /jobs/:id/cancel
+func (h *JobsHandler) CancelJob(c *gin.Context) {
+	jobID, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.quizWorker.CancelJob(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	message := "İş iptal edildi"
+	if job.Status == models.JobStatusRunning {
+		message = "İş çalışıyor; mevcut deneme bittiğinde iptal edilecek"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job.ToResponse(),
+		"message": message,
+	})
+}
+
+// RetryJob handles requeueing a failed or cancelled job
+// POST /jobs/:id/retry
+func (h *JobsHandler) RetryJob(c *gin.Context) {
+	jobID, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.quizWorker.RetryJob(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    job.ToResponse(),
+		"message": "İş yeniden kuyruğa eklendi",
+	})
+}
+
+// parseJobID parses the :id path parameter and responds with 400 if invalid
+func parseJobID(c *gin.Context) (uuid.UUID, bool) {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz iş ID",
+		})
+		return uuid.Nil, false
+	}
+	return jobID, true
+}
+
+// respondJobError maps quiz job errors to HTTP responses
+func respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "İş bulunamadı",
+		})
+	case errors.Is(err, services.ErrJobNotCancellable),
+		errors.Is(err, services.ErrJobNotRetryable),
+		errors.Is(err, services.ErrJobAlreadyActive):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		log.Printf("❌ Quiz job operation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "İş işlemi başarısız oldu",
+			"details": err.Error(),
+		})
+	}
+}
@@ -1,26 +1,55 @@
 package handlers
 
 import (
-	"encoding/json"
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/middleware"
 	"github.com/bookwise/api/internal/models"
+	"github.com/bookwise/api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // QuizHandler handles quiz-related endpoints
-type QuizHandler struct{}
+type QuizHandler struct {
+	quizWorker  *services.QuizWorker
+	rateLimiter *middleware.RateLimiter
+}
+
+// NewQuizHandler creates a new quiz handler. Quizzes queued by GET
+// /quiz/:bookId?lang= count against the generate_quiz rate limit.
+func NewQuizHandler(quizWorker *services.QuizWorker, rateLimiter *middleware.RateLimiter) *QuizHandler {
+	return &QuizHandler{
+		quizWorker:  quizWorker,
+		rateLimiter: rateLimiter,
+	}
+}
 
-// NewQuizHandler creates a new quiz handler
-func NewQuizHandler() *QuizHandler {
-	return &QuizHandler{}
+// SubmitAttemptRequest represents the request body for submitting a quiz attempt
+type SubmitAttemptRequest struct {
+	Answers []models.SubmittedAnswer `json:"answers" binding:"required"` // Per question: a string, or a list for multi_select and ordering
 }
 
-// GetQuiz handles get quiz by book ID
-// GET /quiz/:bookId
+// GetQuiz handles get quiz by book ID. Without difficulty and lang the
+// book's active quiz is returned; a missing quiz in the requested language
+// is queued.
+// GET /quiz/:bookId?view=play|full&difficulty=easy|medium|hard&lang=en|tr
 func (h *QuizHandler) GetQuiz(c *gin.Context) {
+	full, ok := quizView(c)
+	if !ok {
+		return
+	}
+
 	bookIDStr := c.Param("bookId")
 	
 	bookID, err := uuid.Parse(bookIDStr)
@@ -42,6 +71,11 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 		return
 	}
 
+	if c.Query("difficulty") != "" || c.Query("lang") != "" {
+		h.respondQuizVariant(c, &book, c.Query("difficulty"), c.Query("lang"), full)
+		return
+	}
+
 	// Check quiz status
 	switch book.QuizStatus {
 	case "pending":
@@ -69,9 +103,13 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 		return
 	}
 
-	// Get quiz
+	// Get the active quiz version, falling back to the latest completed one
 	var quiz models.Quiz
-	if err := database.DB.Where("book_id = ?", bookID).First(&quiz).Error; err != nil {
+	query := database.DB.Where("book_id = ? AND status = ?", bookID, "completed")
+	if book.QuizID != nil {
+		query = database.DB.Where("id = ?", *book.QuizID)
+	}
+	if err := query.Order("version DESC").First(&quiz).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "Quiz bulunamadı",
@@ -79,36 +117,133 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 		return
 	}
 
-	// Parse questions - try direct array first, then nested format
-	var questions []models.QuizQuestion
-	if err := json.Unmarshal(quiz.Questions, &questions); err != nil {
-		// Try nested format {"quiz": [...]}
-		var quizData models.QuizData
-		if err2 := json.Unmarshal(quiz.Questions, &quizData); err2 != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+	respondQuiz(c, &quiz, full)
+}
+
+// respondQuizVariant writes the book's quiz of a difficulty and language
+// (empty for any). While one is being generated it responds 202; a missing
+// quiz in the requested language is queued for signed-in callers.
+func (h *QuizHandler) respondQuizVariant(c *gin.Context, book *models.Book, difficulty, language string, full bool) {
+	difficulty = strings.ToLower(strings.TrimSpace(difficulty))
+	if difficulty != "" && !slices.Contains(models.Difficulties, difficulty) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz zorluk seviyesi",
+			"details": fmt.Sprintf("difficulty must be one of: %s", strings.Join(models.Difficulties, ", ")),
+		})
+		return
+	}
+	if language != "" {
+		language = services.NormalizeLanguage(language)
+		if !slices.Contains(models.QuizLanguages, language) {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "Quiz verisi okunamadı",
+				"error":   "Desteklenmeyen quiz dili",
+				"details": fmt.Sprintf("lang must be one of: %s", strings.Join(models.QuizLanguages, ", ")),
 			})
 			return
 		}
-		questions = quizData.Quiz
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"id":         quiz.ID,
-			"book_id":    quiz.BookID,
-			"quiz":       questions,
-			"ai_model":   quiz.AIModel,
-			"created_at": quiz.CreatedAt,
-		},
+	quiz, err := services.FindBookQuiz(book, difficulty, language)
+	if err == nil {
+		respondQuiz(c, quiz, full)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("❌ Failed to load %s/%s quiz: %v", difficulty, language, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz yüklenemedi",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if job, err := services.ActiveJob(book.ID, difficulty, language); err == nil {
+		respondQuizJob(c, job, "Bu quiz şu anda oluşturuluyor. Lütfen birkaç saniye sonra tekrar deneyin.")
+		return
+	}
+
+	if language == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":    false,
+			"difficulty": difficulty,
+			"error":      "Bu zorlukta quiz bulunamadı",
+		})
+		return
+	}
+
+	// Don't requeue a quiz whose generation already failed; that is what
+	// the admin retry endpoints are for
+	var failed int64
+	database.DB.Model(&models.Quiz{}).
+		Where("book_id = ? AND status = ? AND language = ? AND difficulty = ?", book.ID, "failed", language, cmp.Or(difficulty, models.DifficultyMedium)).
+		Count(&failed)
+	if failed > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success":  false,
+			"status":   "failed",
+			"language": language,
+			"error":    "Bu dilde quiz oluşturulamadı. Lütfen destek ekibiyle iletişime geçin.",
+		})
+		return
+	}
+
+	// Queuing costs LLM calls: the same guards as POST generate-quiz apply
+	if !middleware.HasRole(c, models.RoleReader) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success":    false,
+			"difficulty": difficulty,
+			"language":   language,
+			"error":      "Bu dilde quiz bulunamadı",
+			"message":    fmt.Sprintf("Oluşturmak için giriş yapıp POST /api/v1/books/%s/generate-quiz isteği gönderin.", book.ID),
+		})
+		return
+	}
+	if !h.rateLimiter.Allow(c, "generate_quiz") {
+		return
+	}
+
+	job, err := h.quizWorker.Enqueue(book.ID, services.QuizJobOptions{
+		Params: models.QuizParams{Difficulty: difficulty, Language: language},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to enqueue %s quiz generation: %v", language, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz oluşturma kuyruğa eklenemedi",
+			"details": err.Error(),
+		})
+		return
+	}
+	respondQuizJob(c, job, "Bu dilde quiz henüz yok; oluşturma işlemi başlatıldı. Lütfen birkaç saniye sonra tekrar deneyin.")
+}
+
+// respondQuizJob writes 202 for a quiz that is waiting for its job
+func respondQuizJob(c *gin.Context, job *models.QuizJob, message string) {
+	status := "pending"
+	if job.Status == models.JobStatusRunning {
+		status = "generating"
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":    false,
+		"status":     status,
+		"difficulty": job.Difficulty,
+		"language":   job.Language,
+		"job_id":     job.ID,
+		"message":    message,
 	})
 }
 
 // GetQuizByID handles get quiz by quiz ID
-// GET /quiz/id/:id
+// GET /quiz/id/:id?view=play|full
 func (h *QuizHandler) GetQuizByID(c *gin.Context) {
+	full, ok := quizView(c)
+	if !ok {
+		return
+	}
+
 	quizIDStr := c.Param("id")
 	
 	quizID, err := uuid.Parse(quizIDStr)
@@ -129,30 +264,281 @@ func (h *QuizHandler) GetQuizByID(c *gin.Context) {
 		return
 	}
 
-	// Parse questions - try direct array first, then nested format
-	var questions []models.QuizQuestion
-	if err := json.Unmarshal(quiz.Questions, &questions); err != nil {
-		// Try nested format {"quiz": [...]}
-		var quizData models.QuizData
-		if err2 := json.Unmarshal(quiz.Questions, &quizData); err2 != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+	// Older versions stay retrievable here so historic attempts remain valid
+	respondQuiz(c, &quiz, full)
+}
+
+// SubmitAttempt handles scoring a quiz attempt server-side
+// POST /quiz/:id/attempts
+func (h *QuizHandler) SubmitAttempt(c *gin.Context) {
+	quizID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz quiz ID",
+		})
+		return
+	}
+
+	var req SubmitAttemptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz istek",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	attempt, results, err := services.SubmitQuizAttempt(quizID, middleware.CurrentUserID(c), req.Answers)
+	switch {
+	case errors.Is(err, services.ErrQuizNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Quiz bulunamadı",
+		})
+		return
+	case errors.Is(err, services.ErrQuizNotPlayable):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Bu quiz çözülemez",
+		})
+		return
+	case errors.Is(err, services.ErrInvalidAnswers):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Cevap sayısı soru sayısıyla eşleşmiyor",
+			"details": err.Error(),
+		})
+		return
+	case err != nil:
+		log.Printf("❌ Failed to submit quiz attempt: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz denemesi kaydedilemedi",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    attempt.ToResponse(results),
+	})
+}
+
+// ListQuizVersions handles listing every quiz version of a book
+// GET /books/:id/quizzes?difficulty=easy|medium|hard&lang=en|tr
+func (h *QuizHandler) ListQuizVersions(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap ID",
+		})
+		return
+	}
+
+	var book models.Book
+	if err := database.DB.Where("id = ?", bookID).First(&book).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Kitap bulunamadı",
+		})
+		return
+	}
+
+	difficulty := c.Query("difficulty")
+	if difficulty != "" && !slices.Contains(models.Difficulties, difficulty) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz zorluk seviyesi",
+			"details": fmt.Sprintf("difficulty must be one of: %s", strings.Join(models.Difficulties, ", ")),
+		})
+		return
+	}
+
+	language := c.Query("lang")
+	if language != "" {
+		language = services.NormalizeLanguage(language)
+		if !slices.Contains(models.QuizLanguages, language) {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "Quiz verisi okunamadı",
+				"error":   "Desteklenmeyen quiz dili",
+				"details": fmt.Sprintf("lang must be one of: %s", strings.Join(models.QuizLanguages, ", ")),
 			})
 			return
 		}
-		questions = quizData.Quiz
+	}
+
+	quizzes, err := services.ListQuizVersions(bookID, difficulty, language)
+	if err != nil {
+		log.Printf("❌ Failed to list quiz versions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz sürümleri listelenemedi",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	responses := make([]*models.QuizVersionResponse, len(quizzes))
+	for i := range quizzes {
+		responses[i] = quizzes[i].ToVersionResponse(book.QuizID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"data":           responses,
+		"active_quiz_id": book.QuizID,
+	})
+}
+
+// ActivateQuizVersion handles making a stored quiz version the active one (rollback)
+// POST /books/:id/quizzes/:quizId/activate
+func (h *QuizHandler) ActivateQuizVersion(c *gin.Context) {
+	bookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz kitap ID",
+		})
+		return
+	}
+
+	quizID, err := uuid.Parse(c.Param("quizId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Geçersiz quiz ID",
+		})
+		return
+	}
+
+	quiz, err := services.ActivateQuizVersion(bookID, quizID)
+	switch {
+	case errors.Is(err, services.ErrQuizNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Quiz bulunamadı",
+		})
+		return
+	case errors.Is(err, services.ErrQuizNotCompleted):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Sadece tamamlanmış quiz sürümleri etkinleştirilebilir",
+		})
+		return
+	case err != nil:
+		log.Printf("❌ Failed to activate quiz version: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz sürümü etkinleştirilemedi",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    quiz.ToVersionResponse(&quiz.ID),
+		"message": fmt.Sprintf("Quiz sürümü %d etkinleştirildi", quiz.Version),
+	})
+}
+
+// quizView reads ?view: "play" (the default) leaves answers, explanations,
+// keywords and rubrics out, "full" keeps them and is reserved for editors.
+// ok is false once an error response has been written.
+func quizView(c *gin.Context) (full bool, ok bool) {
+	switch c.DefaultQuery("view", "play") {
+	case "play":
+		return false, true
+	case "full":
+		if !middleware.HasRole(c, models.RoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Cevapları görmek için editör yetkisi gerekli",
+			})
+			return false, false
+		}
+		return true, true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"error":   "Geçersiz görünüm",
+		"details": "view must be one of: play, full",
+	})
+	return false, false
+}
+
+// respondQuiz writes a quiz with its parsed questions. Unless full is set
+// answers and explanations are left out; they are returned when an attempt
+// is submitted.
+func respondQuiz(c *gin.Context, quiz *models.Quiz, full bool) {
+	questions, err := quiz.ParseQuestions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Quiz verisi okunamadı",
+		})
+		return
+	}
+
+	var quizData interface{} = models.PlayQuestions(questions)
+	if full {
+		quizData = questions
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"id":         quiz.ID,
-			"book_id":    quiz.BookID,
-			"quiz":       questions,
-			"ai_model":   quiz.AIModel,
-			"created_at": quiz.CreatedAt,
+			"id":              quiz.ID,
+			"book_id":         quiz.BookID,
+			"version":         quiz.Version,
+			"difficulty":      quiz.Difficulty,
+			"language":        quiz.Language,
+			"questions_count": quiz.QuestionsCount,
+			"age_group":       quiz.AgeGroup,
+			"focus":           quiz.Focus,
+			"quiz":            quizData,
+			"ai_model":        quiz.AIModel,
+			"prompt_version":  quiz.PromptVersion,
+			"quality_score":   quiz.QualityScore,
+			"quality_issues":  quiz.QualityIssues,
+			"created_at":      quiz.CreatedAt,
 		},
 	})
 }
 
+// acceptedLanguages returns the languages of an Accept-Language header,
+// most preferred first
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			q = parsed
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	languages := make([]string, len(tags))
+	for i, tag := range tags {
+		languages[i] = tag.tag
+	}
+	return languages
+}
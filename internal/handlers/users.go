@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/bookwise/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// UsersHandler handles user endpoints
+type UsersHandler struct{}
+
+// NewUsersHandler creates a new users handler
+func NewUsersHandler() *UsersHandler {
+	return &UsersHandler{}
+}
+
+// GetMe returns the authenticated user
+// GET /users/me
+func (h *UsersHandler) GetMe(c *gin.Context) {
+	user, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Yetkilendirme gerekli",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    user,
+	})
+}
@@ -0,0 +1,211 @@
+// Package isbn normalizes, validates and converts ISBN-10 and ISBN-13 identifiers.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalid is returned when a value is not a valid ISBN-10 or ISBN-13
+var ErrInvalid = errors.New("invalid ISBN")
+
+// ISBN holds both forms of a validated ISBN. ISBN10 is empty for
+// 979-prefixed ISBN-13s, which have no ISBN-10 equivalent.
+type ISBN struct {
+	ISBN10 string
+	ISBN13 string
+}
+
+// Normalize strips an optional "ISBN" prefix, hyphens and whitespace and
+// upper-cases the ISBN-10 check character. It does not validate.
+func Normalize(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 4 && strings.EqualFold(value[:4], "isbn") {
+		value = value[4:]
+		// "ISBN-13: ..." / "ISBN-10 ..." labels
+		for _, label := range []string{"-13", "-10"} {
+			if len(value) > len(label) && strings.HasPrefix(value, label) && strings.ContainsRune(": ", rune(value[len(label)])) {
+				value = value[len(label):]
+			}
+		}
+		value = strings.TrimLeft(value, "-: ")
+	}
+
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range value {
+		switch {
+		case r == '-' || unicode.IsSpace(r):
+			continue
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Parse normalizes and validates value and returns both ISBN forms
+func Parse(value string) (ISBN, error) {
+	normalized := Normalize(value)
+
+	switch len(normalized) {
+	case 10:
+		if !isValid10(normalized) {
+			return ISBN{}, fmt.Errorf("%w: bad ISBN-10 checksum in %q", ErrInvalid, value)
+		}
+		return ISBN{ISBN10: normalized, ISBN13: convert10To13(normalized)}, nil
+	case 13:
+		if !isValid13(normalized) {
+			return ISBN{}, fmt.Errorf("%w: bad ISBN-13 checksum in %q", ErrInvalid, value)
+		}
+		result := ISBN{ISBN13: normalized}
+		if strings.HasPrefix(normalized, "978") {
+			result.ISBN10 = convert13To10(normalized)
+		}
+		return result, nil
+	default:
+		return ISBN{}, fmt.Errorf("%w: %q must have 10 or 13 digits", ErrInvalid, value)
+	}
+}
+
+// Valid reports whether value is a valid ISBN-10 or ISBN-13
+func Valid(value string) bool {
+	_, err := Parse(value)
+	return err == nil
+}
+
+// To13 converts a valid ISBN-10 or ISBN-13 to normalized ISBN-13 form
+func To13(value string) (string, error) {
+	parsed, err := Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.ISBN13, nil
+}
+
+// To10 converts a valid ISBN to ISBN-10 form. 979-prefixed ISBN-13s have
+// no ISBN-10 equivalent and return an error.
+func To10(value string) (string, error) {
+	parsed, err := Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if parsed.ISBN10 == "" {
+		return "", fmt.Errorf("%w: %q has no ISBN-10 form", ErrInvalid, value)
+	}
+	return parsed.ISBN10, nil
+}
+
+// Variants returns every stored form the ISBN may appear in, for lookups
+func (i ISBN) Variants() []string {
+	if i.ISBN10 == "" {
+		return []string{i.ISBN13}
+	}
+	return []string{i.ISBN13, i.ISBN10}
+}
+
+// Stored returns the values a book stores in its isbn and isbn13 columns:
+// isbn keeps the ISBN-10 when one exists and isbn13 always holds the ISBN-13
+func (i ISBN) Stored() (string, string) {
+	if i.ISBN10 == "" {
+		return i.ISBN13, i.ISBN13
+	}
+	return i.ISBN10, i.ISBN13
+}
+
+// Canonical returns the stored isbn and isbn13 values for unvalidated
+// provider or legacy data. Invalid values are only normalized so no data is
+// lost.
+func Canonical(isbnValue, isbn13Value string) (string, string) {
+	for _, candidate := range []string{isbn13Value, isbnValue} {
+		if parsed, err := Parse(candidate); err == nil {
+			return parsed.Stored()
+		}
+	}
+
+	isbnValue, isbn13Value = Normalize(isbnValue), Normalize(isbn13Value)
+	if isbnValue == "" {
+		isbnValue = isbn13Value
+	}
+	return isbnValue, isbn13Value
+}
+
+// String returns the canonical (ISBN-13) form
+func (i ISBN) String() string {
+	return i.ISBN13
+}
+
+func isValid10(value string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := value[i]
+		var digit int
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int(c - '0')
+		case c == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func isValid13(value string) bool {
+	if !strings.HasPrefix(value, "978") && !strings.HasPrefix(value, "979") {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 13; i++ {
+		c := value[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// convert10To13 converts a validated ISBN-10 to ISBN-13
+func convert10To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := (10 - sum%10) % 10
+
+	return fmt.Sprintf("%s%d", body, check)
+}
+
+// convert13To10 converts a validated 978-prefixed ISBN-13 to ISBN-10
+func convert13To10(isbn13 string) string {
+	body := isbn13[3:12]
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+
+	if check == 10 {
+		return body + "X"
+	}
+	return fmt.Sprintf("%s%d", body, check)
+}
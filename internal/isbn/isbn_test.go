@@ -0,0 +1,135 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"978-0-262-03384-8", "9780262033848"},
+		{" 0 262 03384 4 ", "0262033844"},
+		{"026204630x", "026204630X"},
+		{"ISBN 978-0-262-03384-8", "9780262033848"},
+		{"isbn-13: 9780262033848", "9780262033848"},
+		{"ISBN-10 0262033844", "0262033844"},
+		{"ISBN:0262033844", "0262033844"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.value); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ISBN
+		wantErr bool
+	}{
+		{value: "0262033844", want: ISBN{ISBN10: "0262033844", ISBN13: "9780262033848"}},
+		{value: "9780262033848", want: ISBN{ISBN10: "0262033844", ISBN13: "9780262033848"}},
+		{value: "978-0-262-04630-5", want: ISBN{ISBN10: "026204630X", ISBN13: "9780262046305"}},
+		{value: "026204630x", want: ISBN{ISBN10: "026204630X", ISBN13: "9780262046305"}},
+		{value: "9791032305690", want: ISBN{ISBN13: "9791032305690"}},
+		{value: "0262033845", wantErr: true},    // bad ISBN-10 checksum
+		{value: "9780262033849", wantErr: true}, // bad ISBN-13 checksum
+		{value: "9770262033840", wantErr: true}, // not a 978/979 prefix
+		{value: "02620X3844", wantErr: true},    // X only allowed as check character
+		{value: "12345", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.value)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalid", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+		if !Valid(tt.value) {
+			t.Errorf("Valid(%q) = false, want true", tt.value)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value   string
+		want10  string
+		want13  string
+		err10   bool
+		invalid bool
+	}{
+		{value: "0262033844", want10: "0262033844", want13: "9780262033848"},
+		{value: "9780262033848", want10: "0262033844", want13: "9780262033848"},
+		{value: "9780262046305", want10: "026204630X", want13: "9780262046305"},
+		{value: "9791032305690", want13: "9791032305690", err10: true},
+		{value: "0262033845", invalid: true},
+	}
+	for _, tt := range tests {
+		got13, err := To13(tt.value)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("To13(%q) = %q, want error", tt.value, got13)
+			}
+			if _, err := To10(tt.value); err == nil {
+				t.Errorf("To10(%q) want error", tt.value)
+			}
+			continue
+		}
+		if err != nil || got13 != tt.want13 {
+			t.Errorf("To13(%q) = %q, %v, want %q", tt.value, got13, err, tt.want13)
+		}
+
+		got10, err := To10(tt.value)
+		if tt.err10 {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("To10(%q) error = %v, want ErrInvalid", tt.value, err)
+			}
+			continue
+		}
+		if err != nil || got10 != tt.want10 {
+			t.Errorf("To10(%q) = %q, %v, want %q", tt.value, got10, err, tt.want10)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name       string
+		isbn       string
+		isbn13     string
+		wantISBN   string
+		wantISBN13 string
+	}{
+		{"isbn-10 only", "0-262-03384-4", "", "0262033844", "9780262033848"},
+		{"isbn-13 only", "", "978-0-262-03384-8", "0262033844", "9780262033848"},
+		{"isbn-13 in isbn column", "9780262033848", "", "0262033844", "9780262033848"},
+		{"both forms", "0262033844", "9780262033848", "0262033844", "9780262033848"},
+		{"valid isbn13 wins", "0262033844", "9780262046305", "026204630X", "9780262046305"},
+		{"979 prefix", "", "9791032305690", "9791032305690", "9791032305690"},
+		{"invalid values kept", "12-34", "12 3x", "1234", "123X"},
+		{"invalid isbn13 only", "", "12345", "12345", "12345"},
+		{"empty", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		gotISBN, gotISBN13 := Canonical(tt.isbn, tt.isbn13)
+		if gotISBN != tt.wantISBN || gotISBN13 != tt.wantISBN13 {
+			t.Errorf("%s: Canonical(%q, %q) = %q, %q, want %q, %q",
+				tt.name, tt.isbn, tt.isbn13, gotISBN, gotISBN13, tt.wantISBN, tt.wantISBN13)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+// Package middleware contains Gin middleware shared by the API routes.
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Context keys set by the authenticator
+const (
+	userContextKey         = "bookwise.user"          // Authenticated *models.User
+	authDisabledContextKey = "bookwise.auth_disabled" // Set when AUTH_ENABLED=false
+)
+
+// Authenticated users are cached so requests don't hit the database each
+// time; role changes take effect on other requests within userCacheTTL.
+// last_seen_at is written at most once per lastSeenInterval.
+const (
+	userCacheTTL        = 30 * time.Second
+	userCacheMaxEntries = 10000
+	lastSeenInterval    = 5 * time.Minute
+)
+
+// Claims are the JWT claims read by the API
+type Claims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Authenticator verifies HS256 and RS256 JWTs and loads the matching user
+type Authenticator struct {
+	enabled       bool
+	publicReads   bool
+	secret        []byte
+	jwks          *JWKS
+	parser        *jwt.Parser
+	adminSubjects map[string]bool
+	users         *userCache
+}
+
+// NewAuthenticator creates an authenticator from configuration. With auth
+// enabled, at least one of AUTH_JWT_SECRET, AUTH_JWKS_FILE or AUTH_JWKS_URL
+// must be set.
+func NewAuthenticator(cfg *config.Config) (*Authenticator, error) {
+	a := &Authenticator{
+		enabled:     cfg.Auth.Enabled,
+		publicReads: cfg.Auth.PublicReads,
+		secret:      []byte(cfg.Auth.JWTSecret),
+
+		adminSubjects: make(map[string]bool, len(cfg.Auth.AdminSubjects)),
+		users:         newUserCache(userCacheTTL, userCacheMaxEntries),
+	}
+	for _, subject := range cfg.Auth.AdminSubjects {
+		a.adminSubjects[subject] = true
+	}
+
+	if !a.enabled {
+		log.Println("⚠️ Authentication is disabled (AUTH_ENABLED=false); all routes are public")
+		return a, nil
+	}
+
+	if cfg.Auth.JWKSFile != "" || cfg.Auth.JWKSURL != "" {
+		jwks, err := NewJWKS(cfg.Auth.JWKSFile, cfg.Auth.JWKSURL, cfg.Auth.JWKSRefresh)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWKS: %w", err)
+		}
+		a.jwks = jwks
+	}
+
+	methods := []string{}
+	if len(a.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.jwks != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("auth is enabled but none of AUTH_JWT_SECRET, AUTH_JWKS_FILE, AUTH_JWKS_URL is set")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if cfg.Auth.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Auth.Issuer))
+	}
+	if cfg.Auth.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Auth.Audience))
+	}
+	a.parser = jwt.NewParser(options...)
+
+	log.Printf("🔐 Authentication enabled (methods: %s, public reads: %v)", strings.Join(methods, ", "), a.publicReads)
+	return a, nil
+}
+
+// RequireAuth rejects requests without a valid bearer token
+func (a *Authenticator) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.enabled {
+			c.Set(authDisabledContextKey, true)
+			c.Next()
+			return
+		}
+
+		token := bearerToken(c)
+		if token == "" {
+			abortUnauthorized(c, "Yetkilendirme gerekli", "missing bearer token")
+			return
+		}
+		a.authenticate(c, token)
+	}
+}
+
+// OptionalAuth loads the user when a bearer token is sent. A token that is
+// sent but invalid is still rejected.
+func (a *Authenticator) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.enabled {
+			c.Set(authDisabledContextKey, true)
+			c.Next()
+			return
+		}
+
+		if token := bearerToken(c); token != "" {
+			a.authenticate(c, token)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ReadAuth guards read-only routes: optional when AUTH_PUBLIC_READS is
+// true, required otherwise
+func (a *Authenticator) ReadAuth() gin.HandlerFunc {
+	if a.publicReads {
+		return a.OptionalAuth()
+	}
+	return a.RequireAuth()
+}
+
+// authenticate verifies the token, stores the user in the context and
+// continues the chain, or aborts with 401
+func (a *Authenticator) authenticate(c *gin.Context, tokenString string) {
+	claims := &Claims{}
+	_, err := a.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.secret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := token.Header["kid"].(string)
+			return a.jwks.Key(c.Request.Context(), kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+	})
+	if err != nil {
+		abortUnauthorized(c, "Geçersiz token", err.Error())
+		return
+	}
+	if claims.Subject == "" {
+		abortUnauthorized(c, "Geçersiz token", "token has no subject")
+		return
+	}
+
+	user, err := a.loadUser(claims)
+	if err != nil {
+		log.Printf("❌ Failed to load user %q: %v", claims.Subject, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Kullanıcı yüklenemedi",
+		})
+		return
+	}
+
+	c.Set(userContextKey, user)
+	c.Next()
+}
+
+// loadUser returns the user of verified claims, from the cache when it was
+// loaded recently with the same profile claims
+func (a *Authenticator) loadUser(claims *Claims) (*models.User, error) {
+	admin := a.adminSubjects[claims.Subject]
+	if user, ok := a.users.get(claims); ok && (!admin || user.Role == models.RoleAdmin) {
+		return user, nil
+	}
+
+	user, err := upsertUser(claims, admin)
+	if err != nil {
+		return nil, err
+	}
+	a.users.put(user)
+
+	copied := *user
+	return &copied, nil
+}
+
+// upsertUser creates the user on first sight and refreshes profile claims.
+// last_seen_at is only written when it is older than lastSeenInterval.
+// Bootstrap admins are promoted to admin.
+func upsertUser(claims *Claims, admin bool) (*models.User, error) {
+	user := &models.User{}
+	err := database.DB.Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).First(user).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user = &models.User{
+			Issuer:     claims.Issuer,
+			Subject:    claims.Subject,
+			Email:      claims.Email,
+			Name:       claims.Name,
+			LastSeenAt: time.Now(),
+		}
+		err = database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "issuer"}, {Name: "subject"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email", "name", "last_seen_at", "updated_at"}),
+		}).Create(user).Error
+		if err != nil {
+			return nil, err
+		}
+
+		// ID and created_at are not returned when a concurrent request
+		// created the user first; read the stored row
+		if err := database.DB.Where("issuer = ? AND subject = ?", user.Issuer, user.Subject).First(user).Error; err != nil {
+			return nil, err
+		}
+
+	case err != nil:
+		return nil, err
+
+	case user.Email != claims.Email || user.Name != claims.Name || time.Since(user.LastSeenAt) >= lastSeenInterval:
+		err = database.DB.Model(user).Updates(map[string]interface{}{
+			"email":        claims.Email,
+			"name":         claims.Name,
+			"last_seen_at": time.Now(),
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if admin && user.Role != models.RoleAdmin {
+		if err := database.DB.Model(user).Update("role", models.RoleAdmin).Error; err != nil {
+			return nil, err
+		}
+		log.Printf("👑 User %s (%s) promoted to admin via AUTH_ADMIN_SUBJECTS", user.ID, user.Subject)
+	}
+	return user, nil
+}
+
+// userCache keeps recently loaded users by issuer and subject
+type userCache struct {
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[string]userCacheEntry
+}
+
+type userCacheEntry struct {
+	user     models.User
+	loadedAt time.Time
+}
+
+func newUserCache(ttl time.Duration, maxEntries int) *userCache {
+	return &userCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]userCacheEntry),
+	}
+}
+
+// get returns a copy of the cached user of claims. Entries past the TTL or
+// with other profile claims miss, so changes are written to the database.
+func (uc *userCache) get(claims *Claims) (*models.User, bool) {
+	uc.mu.Lock()
+	entry, ok := uc.entries[userCacheKey(claims.Issuer, claims.Subject)]
+	uc.mu.Unlock()
+
+	if !ok || time.Since(entry.loadedAt) >= uc.ttl || entry.user.Email != claims.Email || entry.user.Name != claims.Name {
+		return nil, false
+	}
+	return &entry.user, true
+}
+
+// put caches a copy of user, dropping expired entries when the cache is full
+func (uc *userCache) put(user *models.User) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if len(uc.entries) >= uc.maxEntries {
+		for key, entry := range uc.entries {
+			if time.Since(entry.loadedAt) >= uc.ttl {
+				delete(uc.entries, key)
+			}
+		}
+		if len(uc.entries) >= uc.maxEntries {
+			uc.entries = make(map[string]userCacheEntry)
+		}
+	}
+	uc.entries[userCacheKey(user.Issuer, user.Subject)] = userCacheEntry{user: *user, loadedAt: time.Now()}
+}
+
+func userCacheKey(issuer, subject string) string {
+	return issuer + "\x00" + subject
+}
+
+// CurrentUser returns the authenticated user, if any
+func CurrentUser(c *gin.Context) (*models.User, bool) {
+	value, ok := c.Get(userContextKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
+
+// CurrentUserID returns the authenticated user's ID, or nil
+func CurrentUserID(c *gin.Context) *uuid.UUID {
+	user, ok := CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	return &user.ID
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func abortUnauthorized(c *gin.Context, message, details string) {
+	c.Header("WWW-Authenticate", `Bearer realm="bookwise"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"error":   message,
+		"details": details,
+	})
+}
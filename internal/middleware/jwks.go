@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksMinRefreshInterval limits reloads triggered by unknown key IDs
+const jwksMinRefreshInterval = 1 * time.Minute
+
+// JWKS holds RSA public keys loaded from a JSON Web Key Set file or URL
+type JWKS struct {
+	file        string
+	url         string
+	refresh     time.Duration
+	httpClient  *http.Client
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time
+}
+
+// jwkSet represents a JSON Web Key Set document
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk represents a single JSON Web Key
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// NewJWKS loads keys from file or url (exactly one should be set). Keys from
+// a URL are reloaded every refresh interval and when an unknown key ID is seen.
+func NewJWKS(file, url string, refresh time.Duration) (*JWKS, error) {
+	j := &JWKS{
+		file:    file,
+		url:     url,
+		refresh: refresh,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		keys: map[string]*rsa.PublicKey{},
+	}
+
+	if err := j.load(context.Background()); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Key returns the public key for kid. An empty kid matches the only key
+// of a single-key set.
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key, ok := j.lookup(kid); ok {
+		if j.url == "" || time.Since(j.refreshedAt()) < j.refresh {
+			return key, nil
+		}
+	}
+
+	// Unknown kid or stale keys: reload (rate-limited) and try again
+	if j.url != "" && time.Since(j.refreshedAt()) >= jwksMinRefreshInterval {
+		if err := j.load(ctx); err != nil {
+			log.Printf("⚠️ Failed to refresh JWKS: %v", err)
+		}
+	}
+
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (j *JWKS) lookup(kid string) (*rsa.PublicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+func (j *JWKS) refreshedAt() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.lastRefresh
+}
+
+// load reads the key set and replaces the current keys
+func (j *JWKS) load(ctx context.Context) error {
+	data, err := j.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS contains no RSA signing keys")
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.lastRefresh = time.Now()
+	j.mu.Unlock()
+
+	log.Printf("🔑 Loaded %d JWKS key(s)", len(keys))
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if j.file != "" {
+		data, err := os.ReadFile(j.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS request returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// rsaPublicKey decodes the base64url modulus and exponent
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("bad modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("bad exponent: %w", err)
+	}
+	if len(n) == 0 || len(e) == 0 {
+		return nil, fmt.Errorf("missing modulus or exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitResult is the outcome of taking one token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // Time until the next token, set when not allowed
+}
+
+// RateLimitStore takes tokens from named token buckets
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rule config.RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimiter applies the configured rules to requests. Clients are keyed
+// by user ID when authenticated, otherwise by IP address.
+type RateLimiter struct {
+	enabled bool
+	backend string
+	rules   map[string]config.RateLimitRule
+	store   RateLimitStore
+}
+
+// NewRateLimiter creates a limiter with the store selected by
+// RATE_LIMIT_BACKEND. The redis backend requires database.InitRedis first.
+func NewRateLimiter(cfg *config.Config) (*RateLimiter, error) {
+	l := &RateLimiter{
+		enabled: cfg.RateLimit.Enabled,
+		backend: cfg.RateLimit.Backend,
+		rules:   cfg.RateLimit.Rules,
+	}
+	if !l.enabled {
+		log.Println("⚠️ Rate limiting is disabled (RATE_LIMIT_ENABLED=false)")
+		return l, nil
+	}
+
+	switch l.backend {
+	case "memory":
+		l.store = NewMemoryRateLimitStore()
+	case "redis":
+		l.store = NewRedisRateLimitStore()
+	case "postgres":
+		l.store = NewPostgresRateLimitStore()
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q (expected memory, redis or postgres)", l.backend)
+	}
+
+	log.Printf("🚦 Rate limiting enabled (backend: %s, rules: %d)", l.backend, len(l.rules))
+	return l, nil
+}
+
+// Limit returns middleware enforcing the named rule. Unknown rules and
+// disabled limiting let every request through. Place it after the auth
+// middleware so authenticated users get their own bucket.
+func (l *RateLimiter) Limit(ruleName string) gin.HandlerFunc {
+	rule, ok := l.rules[ruleName]
+	if !l.enabled || !ok || rule.Limit <= 0 || rule.Period <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		if l.Allow(c, ruleName) {
+			c.Next()
+		}
+	}
+}
+
+// Allow takes a token of the named rule for the request, for handlers
+// that only limit some requests of a route. It responds 429 and returns
+// false when the limit is exceeded.
+func (l *RateLimiter) Allow(c *gin.Context, ruleName string) bool {
+	rule, ok := l.rules[ruleName]
+	if !l.enabled || !ok || rule.Limit <= 0 || rule.Period <= 0 {
+		return true
+	}
+
+	key := fmt.Sprintf("rl:%s:%s", ruleName, rateLimitClient(c))
+
+	result, err := l.store.Take(c.Request.Context(), key, rule)
+	if err != nil {
+		// Fail open: a broken limiter backend shouldn't take the API down
+		log.Printf("⚠️ Rate limit check failed for %s: %v", key, err)
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(rule.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+	if !result.Allowed {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"success":     false,
+			"error":       "Çok fazla istek, lütfen daha sonra tekrar deneyin",
+			"details":     fmt.Sprintf("rate limit %q exceeded (%d per %s)", ruleName, rule.Limit, rule.Period),
+			"retry_after": retryAfter,
+		})
+		return false
+	}
+	return true
+}
+
+// rateLimitClient identifies the caller for bucket keys. ClientIP only
+// reads forwarding headers from TRUSTED_PROXIES (see SetTrustedProxies in
+// main), so clients can't pick a fresh bucket per request.
+func rateLimitClient(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		return "user:" + user.ID.String()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// refillBucket returns the tokens in a bucket after refilling it for the
+// time elapsed since the last update
+func refillBucket(tokens float64, elapsed time.Duration, rule config.RateLimitRule) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * float64(rule.Limit) / rule.Period.Seconds()
+	}
+	return math.Min(tokens, float64(rule.Limit))
+}
+
+// takeToken takes one token from a refilled bucket and reports the result
+// along with the tokens left
+func takeToken(tokens float64, rule config.RateLimitRule) (RateLimitResult, float64) {
+	if tokens >= 1 {
+		tokens--
+		return RateLimitResult{Allowed: true, Remaining: int(tokens)}, tokens
+	}
+	perToken := rule.Period.Seconds() / float64(rule.Limit)
+	wait := time.Duration((1 - tokens) * perToken * float64(time.Second))
+	return RateLimitResult{Allowed: false, RetryAfter: wait}, tokens
+}
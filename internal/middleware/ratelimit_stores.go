@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// memoryBucketIdle is how long an untouched in-memory bucket is kept
+const memoryBucketIdle = 2 * time.Hour
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Limits are per
+// replica; use the redis or postgres backend when running several.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   map[string]*memoryBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rule config.RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(rule.Limit), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens := refillBucket(bucket.tokens, now.Sub(bucket.updatedAt), rule)
+	result, tokens := takeToken(tokens, rule)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	return result, nil
+}
+
+// sweep drops idle buckets so memory doesn't grow with every client seen
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryBucketIdle {
+		return
+	}
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) > memoryBucketIdle {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// redisTakeScript refills and takes from a bucket stored as a hash, in one
+// atomic step. Returns {allowed, tokens}; tokens is a string to keep the
+// fraction.
+var redisTakeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period_ms = tonumber(ARGV[2])
+local now_ms = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated_ms")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil then
+  tokens = limit
+  updated = now_ms
+end
+
+local elapsed = math.max(0, now_ms - updated)
+tokens = math.min(limit, tokens + elapsed * limit / period_ms)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_ms", now_ms)
+redis.call("PEXPIRE", KEYS[1], period_ms)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisRateLimitStore keeps buckets in Redis, shared by all replicas
+type RedisRateLimitStore struct{}
+
+// NewRedisRateLimitStore creates a store using database.Redis
+func NewRedisRateLimitStore() *RedisRateLimitStore {
+	return &RedisRateLimitStore{}
+}
+
+// Take implements RateLimitStore
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, rule config.RateLimitRule) (RateLimitResult, error) {
+	if database.Redis == nil {
+		return RateLimitResult{}, fmt.Errorf("redis is not connected")
+	}
+
+	values, err := redisTakeScript.Run(ctx, database.Redis, []string{key},
+		rule.Limit, rule.Period.Milliseconds(), time.Now().UnixMilli()).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensText, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("bad token count %q: %w", tokensText, err)
+	}
+
+	if allowed == 1 {
+		return RateLimitResult{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	result, _ := takeToken(tokens, rule)
+	return result, nil
+}
+
+// PostgresRateLimitStore keeps buckets in the rate_limit_buckets table,
+// shared by all replicas without needing Redis
+type PostgresRateLimitStore struct{}
+
+// NewPostgresRateLimitStore creates a store using database.DB
+func NewPostgresRateLimitStore() *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{}
+}
+
+// Take implements RateLimitStore. The refill and take happen in a single
+// upsert, so concurrent requests can't spend the same token.
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, rule config.RateLimitRule) (RateLimitResult, error) {
+	db := database.DB.WithContext(ctx)
+	rate := float64(rule.Limit) / rule.Period.Seconds()
+	refilled := `LEAST(?::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM (NOW() - rate_limit_buckets.updated_at))::float8 * ?::float8)`
+
+	var tokens []float64
+	err := db.Raw(`
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?, ?, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET tokens = `+refilled+` - 1, updated_at = NOW()
+		WHERE `+refilled+` >= 1
+		RETURNING tokens`,
+		key, float64(rule.Limit-1),
+		rule.Limit, rate,
+		rule.Limit, rate,
+	).Scan(&tokens).Error
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(tokens) > 0 {
+		return RateLimitResult{Allowed: true, Remaining: int(tokens[0])}, nil
+	}
+
+	// Bucket is empty: read it to tell the client when to retry
+	var bucket models.RateLimitBucket
+	if err := db.Where("key = ?", key).First(&bucket).Error; err != nil {
+		return RateLimitResult{}, err
+	}
+	result, _ := takeToken(refillBucket(bucket.Tokens, time.Since(bucket.UpdatedAt), rule), rule)
+	if result.Allowed {
+		// Refilled between the two statements; deny this one anyway
+		result = RateLimitResult{RetryAfter: time.Second}
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRefillBucket(t *testing.T) {
+	rule := config.RateLimitRule{Limit: 10, Period: time.Minute} // One token every 6s
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"no time elapsed", 3, 0, 3},
+		{"one token", 3, 6 * time.Second, 4},
+		{"partial token", 0, 3 * time.Second, 0.5},
+		{"capped at the limit", 8, time.Hour, 10},
+		{"clock went backwards", 3, -time.Minute, 3},
+		{"full bucket", 10, time.Second, 10},
+	}
+	for _, tt := range tests {
+		if got := refillBucket(tt.tokens, tt.elapsed, rule); got != tt.want {
+			t.Errorf("%s: refillBucket(%v, %v) = %v, want %v", tt.name, tt.tokens, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestTakeToken(t *testing.T) {
+	rule := config.RateLimitRule{Limit: 10, Period: time.Minute}
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		want       RateLimitResult
+		wantTokens float64
+	}{
+		{"full bucket", 10, RateLimitResult{Allowed: true, Remaining: 9}, 9},
+		{"last token", 1, RateLimitResult{Allowed: true, Remaining: 0}, 0},
+		{"partial tokens round down", 2.5, RateLimitResult{Allowed: true, Remaining: 1}, 1.5},
+		{"empty bucket", 0, RateLimitResult{RetryAfter: 6 * time.Second}, 0},
+		{"almost a token", 0.5, RateLimitResult{RetryAfter: 3 * time.Second}, 0.5},
+	}
+	for _, tt := range tests {
+		got, tokens := takeToken(tt.tokens, rule)
+		if got != tt.want || tokens != tt.wantTokens {
+			t.Errorf("%s: takeToken(%v) = %+v, %v, want %+v, %v", tt.name, tt.tokens, got, tokens, tt.want, tt.wantTokens)
+		}
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	rule := config.RateLimitRule{Limit: 3, Period: time.Hour}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "ip:1.2.3.4", rule)
+		if err != nil || !result.Allowed || result.Remaining != i {
+			t.Fatalf("Take() = %+v, %v, want allowed with %d remaining", result, err, i)
+		}
+	}
+
+	result, err := store.Take(ctx, "ip:1.2.3.4", rule)
+	if err != nil || result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > 20*time.Minute {
+		t.Errorf("Take() over the limit = %+v, %v, want denied with a retry within 20m", result, err)
+	}
+
+	// Buckets are per key
+	if result, err := store.Take(ctx, "ip:5.6.7.8", rule); err != nil || !result.Allowed {
+		t.Errorf("Take() for another client = %+v, %v, want allowed", result, err)
+	}
+}
+
+// newTestLimitedRouter returns a router allowing one request per hour per
+// client, trusting the given proxies
+func newTestLimitedRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := NewRateLimiter(&config.Config{RateLimit: config.RateLimitConfig{
+		Enabled: true,
+		Backend: "memory",
+		Rules:   map[string]config.RateLimitRule{"default": {Limit: 1, Period: time.Hour}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRateLimiter() error: %v", err)
+	}
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies() error: %v", err)
+	}
+	router.GET("/", limiter.Limit("default"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		want           []int // Status of each request, sent from the same address
+	}{
+		{"spoofed headers share the caller's bucket", nil, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{"trusted proxy forwards client IPs", []string{"192.0.2.0/24"}, []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		router := newTestLimitedRouter(t, tt.trustedProxies)
+		forwarded := []string{"", "203.0.113.7", "203.0.113.8"}
+
+		for i, want := range tt.want {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:40000"
+			if forwarded[i] != "" {
+				req.Header.Set("X-Forwarded-For", forwarded[i])
+				req.Header.Set("X-Real-IP", forwarded[i])
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s: request %d (X-Forwarded-For %q) = %d, want %d", tt.name, i+1, forwarded[i], w.Code, want)
+			}
+		}
+	}
+}
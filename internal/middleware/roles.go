@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole rejects requests whose user lacks the given role (or a more
+// privileged one). It must run after RequireAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Bu işlem için yetkiniz yok",
+				"details": "requires role " + role,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasRole reports whether the current user has at least the given role.
+// Every request passes when authentication is disabled.
+func HasRole(c *gin.Context, role string) bool {
+	if c.GetBool(authDisabledContextKey) {
+		return true
+	}
+	user, ok := CurrentUser(c)
+	return ok && user.HasRole(role)
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// QuizAttempt stores a submitted and server-side scored quiz attempt.
+// It references the exact quiz version that was played, so attempts stay
+// valid after the book's active quiz changes.
+type QuizAttempt struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	QuizID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"quiz_id"`
+	BookID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"book_id"`
+	UserID    *uuid.UUID     `gorm:"type:uuid;index" json:"user_id,omitempty"` // Player who submitted the attempt
+	Score     int            `gorm:"not null" json:"score"`                    // Number of correct answers
+	Points    float64        `gorm:"not null;default:0" json:"points"`         // Sum of per-question credit, including partial credit
+	Total     int            `gorm:"not null" json:"total"`                    // Number of questions
+	Answers   datatypes.JSON `gorm:"type:jsonb;not null" json:"answers"`       // []QuizAttemptAnswer
+	CreatedAt time.Time      `json:"created_at"`
+
+	// Relationship
+	Quiz Quiz `gorm:"foreignKey:QuizID" json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (QuizAttempt) TableName() string {
+	return "quiz_attempts"
+}
+
+// QuizAttemptAnswer is the graded answer to a single question. List
+// answers (multi_select, ordering) use Answers and CorrectAnswers.
+type QuizAttemptAnswer struct {
+	Question       int      `json:"question"` // 1-based question number
+	Type           string   `json:"type,omitempty"`
+	Answer         string   `json:"answer"` // Submitted answer, empty if skipped
+	Answers        []string `json:"answers,omitempty"`
+	CorrectAnswer  string   `json:"correct_answer"`
+	CorrectAnswers []string `json:"correct_answers,omitempty"`
+	Correct        bool     `json:"correct"`
+	Score          float64  `json:"score"` // Credit between 0 and 1
+	Explanation    string   `json:"explanation"`
+}
+
+// SubmittedAnswer is the answer to one question as sent by a player: a
+// string, or a list of strings for multi_select and ordering questions.
+// A comma-separated string is accepted as a list too.
+type SubmittedAnswer struct {
+	Values   []string
+	IsString bool // Sent as a single string, so Items splits it on commas
+}
+
+// UnmarshalJSON accepts a string, a list of strings or null
+func (a *SubmittedAnswer) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*a = SubmittedAnswer{IsString: true}
+		if strings.TrimSpace(text) != "" {
+			a.Values = []string{text}
+		}
+		return nil
+	}
+
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("answer must be a string or a list of strings")
+	}
+	*a = SubmittedAnswer{Values: items}
+	return nil
+}
+
+// Text returns a single-value answer
+func (a SubmittedAnswer) Text() string {
+	return strings.TrimSpace(strings.Join(a.Values, ","))
+}
+
+// Items returns a list answer. Only an answer sent as a string is split on
+// commas; items of a list are kept whole, commas included.
+func (a SubmittedAnswer) Items() []string {
+	items := a.Values
+	if a.IsString && len(items) == 1 {
+		items = strings.Split(items[0], ",")
+	}
+
+	trimmed := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
+
+// QuizAttemptResponse represents the API response for a scored attempt
+type QuizAttemptResponse struct {
+	ID         uuid.UUID           `json:"id"`
+	QuizID     uuid.UUID           `json:"quiz_id"`
+	BookID     uuid.UUID           `json:"book_id"`
+	UserID     *uuid.UUID          `json:"user_id,omitempty"`
+	Score      int                 `json:"score"`
+	Points     float64             `json:"points"`
+	Total      int                 `json:"total"`
+	Percentage float64             `json:"percentage"`
+	Results    []QuizAttemptAnswer `json:"results"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// ToResponse converts QuizAttempt model to QuizAttemptResponse
+func (a *QuizAttempt) ToResponse(results []QuizAttemptAnswer) *QuizAttemptResponse {
+	// Attempts stored before partial credit only have a score
+	points := a.Points
+	if points == 0 {
+		points = float64(a.Score)
+	}
+
+	percentage := 0.0
+	if a.Total > 0 {
+		percentage = points * 100 / float64(a.Total)
+	}
+
+	return &QuizAttemptResponse{
+		ID:         a.ID,
+		QuizID:     a.QuizID,
+		BookID:     a.BookID,
+		UserID:     a.UserID,
+		Score:      a.Score,
+		Points:     points,
+		Total:      a.Total,
+		Percentage: percentage,
+		Results:    results,
+		CreatedAt:  a.CreatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSubmittedAnswer(t *testing.T) {
+	tests := []struct {
+		json      string
+		wantText  string
+		wantItems []string
+	}{
+		{`"B"`, "B", []string{"B"}},
+		{`" A, C "`, "A, C", []string{"A", "C"}},
+		{`["A) Paris, France", "C) Rome"]`, "A) Paris, France,C) Rome", []string{"A) Paris, France", "C) Rome"}},
+		{`["A", " ", "C "]`, "A, ,C", []string{"A", "C"}},
+		{`""`, "", []string{}},
+		{`"   "`, "", []string{}},
+		{`null`, "", []string{}},
+		{`[]`, "", []string{}},
+	}
+	for _, tt := range tests {
+		var answer SubmittedAnswer
+		if err := json.Unmarshal([]byte(tt.json), &answer); err != nil {
+			t.Errorf("Unmarshal(%s) unexpected error: %v", tt.json, err)
+			continue
+		}
+		if got := answer.Text(); got != tt.wantText {
+			t.Errorf("Unmarshal(%s).Text() = %q, want %q", tt.json, got, tt.wantText)
+		}
+		if got := answer.Items(); !slices.Equal(got, tt.wantItems) {
+			t.Errorf("Unmarshal(%s).Items() = %q, want %q", tt.json, got, tt.wantItems)
+		}
+	}
+}
+
+func TestSubmittedAnswerInvalid(t *testing.T) {
+	for _, value := range []string{`1`, `true`, `{"answer": "A"}`, `[1, 2]`} {
+		var answer SubmittedAnswer
+		if err := json.Unmarshal([]byte(value), &answer); err == nil {
+			t.Errorf("Unmarshal(%s) = %+v, want error", value, answer)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +26,8 @@ type Book struct {
 	ThumbnailURL  string         `json:"thumbnail_url,omitempty"`
 	SourceData    datatypes.JSON `gorm:"type:jsonb" json:"source_data,omitempty"`      // Raw data for debugging
 	DataSources   pq.StringArray `gorm:"type:text[]" json:"data_sources,omitempty"`    // ["google_books", "open_library"]
+	Provenance    datatypes.JSON `gorm:"type:jsonb" json:"-"`                          // Per-field data sources, see BookProvenance
+	LockedFields  pq.StringArray `gorm:"type:text[]" json:"locked_fields,omitempty"`   // Manually edited fields that refreshes keep
 	QuizID        *uuid.UUID     `gorm:"type:uuid" json:"quiz_id,omitempty"`
 	QuizStatus    string         `gorm:"default:'pending'" json:"quiz_status"`         // "pending", "generating", "completed", "failed"
 	CreatedAt     time.Time      `json:"created_at"`
@@ -36,6 +39,45 @@ func (Book) TableName() string {
 	return "books"
 }
 
+// Book field names used in merge policies and provenance
+const (
+	FieldTitle         = "title"
+	FieldAuthors       = "authors"
+	FieldISBN          = "isbn"
+	FieldISBN13        = "isbn13"
+	FieldDescription   = "description"
+	FieldPublisher     = "publisher"
+	FieldPublishedDate = "published_date"
+	FieldPageCount     = "page_count"
+	FieldCategories    = "categories"
+	FieldLanguage      = "language"
+	FieldCoverURL      = "cover_url"
+	FieldThumbnailURL  = "thumbnail_url"
+)
+
+// BookProvenance maps a book field to the data sources its value came from.
+// Most fields have a single source; merged fields (e.g. a union of
+// categories) list every contributing source.
+type BookProvenance map[string][]string
+
+// GetProvenance decodes the stored per-field provenance
+func (b *Book) GetProvenance() BookProvenance {
+	provenance := BookProvenance{}
+	if len(b.Provenance) == 0 {
+		return provenance
+	}
+	if err := json.Unmarshal(b.Provenance, &provenance); err != nil {
+		return BookProvenance{}
+	}
+	return provenance
+}
+
+// SetProvenance stores the per-field provenance
+func (b *Book) SetProvenance(provenance BookProvenance) {
+	data, _ := json.Marshal(provenance)
+	b.Provenance = datatypes.JSON(data)
+}
+
 // BookResponse represents the API response for a book
 type BookResponse struct {
 	ID            uuid.UUID `json:"id"`
@@ -52,6 +94,8 @@ type BookResponse struct {
 	CoverURL      string    `json:"cover_url,omitempty"`
 	ThumbnailURL  string    `json:"thumbnail_url,omitempty"`
 	DataSources   []string  `json:"data_sources,omitempty"`
+	Provenance    BookProvenance `json:"provenance,omitempty"` // Only set when requested
+	LockedFields  []string  `json:"locked_fields,omitempty"`
 	QuizStatus    string    `json:"quiz_status"`
 	QuizID        *uuid.UUID `json:"quiz_id,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -74,6 +118,7 @@ func (b *Book) ToResponse() *BookResponse {
 		CoverURL:      b.CoverURL,
 		ThumbnailURL:  b.ThumbnailURL,
 		DataSources:   []string(b.DataSources),
+		LockedFields:  []string(b.LockedFields),
 		QuizStatus:    b.QuizStatus,
 		QuizID:        b.QuizID,
 		CreatedAt:     b.CreatedAt,
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// DataSourceManual marks data entered by hand rather than by a provider
+const DataSourceManual = "manual"
+
+// EditableBookFields are the fields that can be corrected manually and
+// refreshed from providers. ISBNs identify the book and are not editable.
+var EditableBookFields = []string{
+	FieldTitle,
+	FieldAuthors,
+	FieldDescription,
+	FieldPublisher,
+	FieldPublishedDate,
+	FieldPageCount,
+	FieldCategories,
+	FieldLanguage,
+	FieldCoverURL,
+	FieldThumbnailURL,
+}
+
+// IsEditableBookField reports whether field is one of EditableBookFields
+func IsEditableBookField(field string) bool {
+	return slices.Contains(EditableBookFields, field)
+}
+
+// FieldValue returns the value of an editable field: a string, []string or int
+func (b *Book) FieldValue(field string) interface{} {
+	switch field {
+	case FieldTitle:
+		return b.Title
+	case FieldAuthors:
+		return []string(b.Authors)
+	case FieldDescription:
+		return b.Description
+	case FieldPublisher:
+		return b.Publisher
+	case FieldPublishedDate:
+		return b.PublishedDate
+	case FieldPageCount:
+		return b.PageCount
+	case FieldCategories:
+		return []string(b.Categories)
+	case FieldLanguage:
+		return b.Language
+	case FieldCoverURL:
+		return b.CoverURL
+	case FieldThumbnailURL:
+		return b.ThumbnailURL
+	default:
+		return nil
+	}
+}
+
+// CopyField sets an editable field to its value in from
+func (b *Book) CopyField(field string, from *Book) {
+	switch field {
+	case FieldTitle:
+		b.Title = from.Title
+	case FieldAuthors:
+		b.Authors = append(pq.StringArray(nil), from.Authors...)
+	case FieldDescription:
+		b.Description = from.Description
+	case FieldPublisher:
+		b.Publisher = from.Publisher
+	case FieldPublishedDate:
+		b.PublishedDate = from.PublishedDate
+	case FieldPageCount:
+		b.PageCount = from.PageCount
+	case FieldCategories:
+		b.Categories = append(pq.StringArray(nil), from.Categories...)
+	case FieldLanguage:
+		b.Language = from.Language
+	case FieldCoverURL:
+		b.CoverURL = from.CoverURL
+	case FieldThumbnailURL:
+		b.ThumbnailURL = from.ThumbnailURL
+	}
+}
+
+// IsLocked reports whether field was locked by a manual edit
+func (b *Book) IsLocked(field string) bool {
+	return slices.Contains(b.LockedFields, field)
+}
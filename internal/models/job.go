@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Quiz job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// QuizJob represents a persistent quiz generation job.
+// Jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED so multiple API
+// replicas can share the queue safely.
+type QuizJob struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	BookID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"book_id"`
+	Status      string     `gorm:"not null;default:'queued'" json:"status"` // "queued", "running", "completed", "failed", "cancelled"
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:3" json:"max_attempts"`
+	RunAfter    time.Time  `gorm:"not null" json:"run_after"` // Not claimed before this time (retry backoff)
+	LockedBy    string     `json:"locked_by,omitempty"`       // Worker that claimed the job
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+
+	QuizParams      `gorm:"embedded"` // Parameters of the quiz to generate
+	Force           bool              `gorm:"not null;default:false" json:"force"`            // Generate a new quiz version even if one exists
+	CancelRequested bool              `gorm:"not null;default:false" json:"cancel_requested"` // Asks the running worker to discard its result
+	StartedAt       *time.Time        `json:"started_at,omitempty"`                           // First time the job was claimed
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`                          // Set when the job reaches a final status
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+
+	// Relationship
+	AttemptHistory []QuizJobAttempt `gorm:"foreignKey:JobID" json:"attempt_history,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (QuizJob) TableName() string {
+	return "quiz_jobs"
+}
+
+// IsFinal reports whether the job has reached a final status
+func (j *QuizJob) IsFinal() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// Duration returns how long the job has been (or was) processed
+func (j *QuizJob) Duration() time.Duration {
+	if j.StartedAt == nil {
+		return 0
+	}
+	if j.FinishedAt != nil {
+		return j.FinishedAt.Sub(*j.StartedAt)
+	}
+	return time.Since(*j.StartedAt)
+}
+
+// IsActive reports whether the job is still waiting or running
+func (j *QuizJob) IsActive() bool {
+	return j.Status == JobStatusQueued || j.Status == JobStatusRunning
+}
+
+// Quiz job attempt outcomes
+const (
+	AttemptStatusRunning   = "running"
+	AttemptStatusSucceeded = "succeeded"
+	AttemptStatusFailed    = "failed"
+	AttemptStatusCancelled = "cancelled"
+)
+
+// QuizJobAttempt records a single execution of a quiz job
+type QuizJobAttempt struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	JobID    uuid.UUID `gorm:"type:uuid;not null;index" json:"job_id"`
+	Attempt  int       `gorm:"not null" json:"attempt"`
+	WorkerID string    `json:"worker_id"`
+	Status   string    `gorm:"not null" json:"status"` // "running", "succeeded", "failed", "cancelled"
+	Error    string    `gorm:"type:text" json:"error,omitempty"`
+
+	GenerationErrors pq.StringArray `gorm:"type:text[]" json:"generation_errors,omitempty"` // Error of every generateQuizAttempt call
+	StartedAt        time.Time      `json:"started_at"`
+	FinishedAt       *time.Time     `json:"finished_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (QuizJobAttempt) TableName() string {
+	return "quiz_job_attempts"
+}
+
+// QuizJobResponse represents the API response for a quiz job
+type QuizJobResponse struct {
+	*QuizJob
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+// ToResponse converts QuizJob model to QuizJobResponse
+func (j *QuizJob) ToResponse() *QuizJobResponse {
+	return &QuizJobResponse{
+		QuizJob:    j,
+		DurationMs: j.Duration().Milliseconds(),
+	}
+}
+
+// ToPublicResponse converts QuizJob model to QuizJobResponse without the
+// error texts of the job and its attempts, which quote raw LLM output
+func (j *QuizJob) ToPublicResponse() *QuizJobResponse {
+	job := *j
+	job.LastError = ""
+	job.AttemptHistory = make([]QuizJobAttempt, len(j.AttemptHistory))
+	for i, attempt := range j.AttemptHistory {
+		attempt.Error = ""
+		attempt.GenerationErrors = nil
+		job.AttemptHistory[i] = attempt
+	}
+	return job.ToResponse()
+}
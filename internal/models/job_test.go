@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestQuizJobToPublicResponse(t *testing.T) {
+	job := &QuizJob{
+		ID:        uuid.New(),
+		Status:    JobStatusFailed,
+		Attempts:  1,
+		LastError: "failed to parse quiz JSON: Content: {...}",
+		AttemptHistory: []QuizJobAttempt{{
+			Attempt:          1,
+			Status:           AttemptStatusFailed,
+			Error:            "failed to generate quiz after 1 attempts: ...",
+			GenerationErrors: []string{"gemini api call failed: ..."},
+		}},
+	}
+
+	public := job.ToPublicResponse()
+	if public.LastError != "" {
+		t.Errorf("LastError = %q, want empty", public.LastError)
+	}
+	if len(public.AttemptHistory) != 1 {
+		t.Fatalf("AttemptHistory has %d attempts, want 1", len(public.AttemptHistory))
+	}
+	attempt := public.AttemptHistory[0]
+	if attempt.Error != "" || attempt.GenerationErrors != nil {
+		t.Errorf("attempt errors = %q, %q, want none", attempt.Error, attempt.GenerationErrors)
+	}
+	if public.ID != job.ID || public.Status != job.Status || attempt.Status != AttemptStatusFailed {
+		t.Errorf("public response lost job fields: %+v", public.QuizJob)
+	}
+
+	// The stored job is not modified
+	if job.LastError == "" || job.AttemptHistory[0].Error == "" || len(job.AttemptHistory[0].GenerationErrors) != 1 {
+		t.Errorf("ToPublicResponse modified the job: %+v", job)
+	}
+	if full := job.ToResponse(); full.LastError != job.LastError {
+		t.Errorf("ToResponse().LastError = %q, want %q", full.LastError, job.LastError)
+	}
+}
@@ -1,21 +1,28 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/datatypes"
 )
 
 // Quiz represents a quiz for a book
 type Quiz struct {
 	ID         uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	BookID     uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"book_id"` // Each book has one quiz
+	BookID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"book_id"` // A book can have many quiz versions; Book.QuizID points to the active one
+	Version    int            `gorm:"not null;default:0" json:"version"`       // 1, 2, ... for completed quizzes; 0 for failed records
 	Questions  datatypes.JSON `gorm:"type:jsonb;not null" json:"questions"`
-	AIModel    string         `gorm:"default:'gpt-4o-mini'" json:"ai_model"`
+	AIModel    string         `json:"ai_model"` // "provider/model", e.g. "gemini/gemini-1.5-flash"
 	Status     string         `gorm:"default:'completed'" json:"status"` // "completed", "failed", "retrying"
 	RetryCount int            `gorm:"default:0" json:"retry_count"`
 	ErrorLog   string         `gorm:"type:text" json:"error_log,omitempty"`
+	QuizParams `gorm:"embedded"`
+	PromptVersion string      `json:"prompt_version,omitempty"` // Template that produced the quiz, "quiz.<language>@<hash>"; empty before prompt templates
+	QualityScore  *int           `json:"quality_score,omitempty"`                // 0-100 from the quality checks; nil before quality scoring and for failed records
+	QualityIssues pq.StringArray `gorm:"type:text[]" json:"quality_issues,omitempty"` // Issues that lowered the score without rejecting the quiz
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	
@@ -28,14 +35,128 @@ func (Quiz) TableName() string {
 	return "quizzes"
 }
 
-// QuizQuestion represents a single quiz question
+// Quiz difficulty levels
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Difficulties lists the quiz difficulty levels
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// Target age groups of a quiz
+const (
+	AgeGroupChildren = "children" // 7-12
+	AgeGroupTeen     = "teen"     // 13-17
+	AgeGroupAdult    = "adult"
+)
+
+// AgeGroups lists the target age groups
+var AgeGroups = []string{AgeGroupChildren, AgeGroupTeen, AgeGroupAdult}
+
+// Quiz focus areas
+const (
+	FocusPlot       = "plot"
+	FocusCharacters = "characters"
+	FocusThemes     = "themes"
+	FocusAuthor     = "author"
+)
+
+// QuizFocuses lists the quiz focus areas
+var QuizFocuses = []string{FocusPlot, FocusCharacters, FocusThemes, FocusAuthor}
+
+// Quiz languages, ISO 639-1 codes
+const (
+	LanguageTurkish = "tr" // Quizzes generated before language selection are Turkish
+	LanguageEnglish = "en"
+)
+
+// QuizLanguages lists the languages quizzes can be generated in
+var QuizLanguages = []string{LanguageEnglish, LanguageTurkish}
+
+// QuizParams are the parameters a quiz is generated with. They are stored
+// on both the generation job and the resulting quiz. Quizzes of different
+// difficulties or languages coexist for the same book.
+type QuizParams struct {
+	Difficulty     string         `gorm:"not null;default:'medium';index" json:"difficulty"`
+	Language       string         `gorm:"not null;default:'tr';index" json:"language"`
+	QuestionsCount int            `gorm:"not null;default:0" json:"questions_count"` // 0 on a job: QUIZ_QUESTIONS_COUNT
+	AgeGroup       string         `json:"age_group,omitempty"`                       // Empty: no specific audience
+	Focus          pq.StringArray `gorm:"type:text[]" json:"focus,omitempty"`         // Empty: every aspect of the book
+}
+
+// Question types
+const (
+	QuestionMultipleChoice = "multiple_choice" // One correct option out of four
+	QuestionTrueFalse      = "true_false"      // Answer is "true" or "false"
+	QuestionMultiSelect    = "multi_select"    // Every correct option must be picked
+	QuestionOrdering       = "ordering"        // Put the options in the right order
+	QuestionFillBlank      = "fill_blank"      // Fill in the "___" in the question
+	QuestionShortAnswer    = "short_answer"    // Free text, graded by rubric keywords
+)
+
+// QuestionTypes lists the supported question types
+var QuestionTypes = []string{
+	QuestionMultipleChoice,
+	QuestionTrueFalse,
+	QuestionMultiSelect,
+	QuestionOrdering,
+	QuestionFillBlank,
+	QuestionShortAnswer,
+}
+
+// QuizQuestion represents a single quiz question. Which fields are used
+// depends on Type:
+//   - multiple_choice: Options ("A) ...") and Answer, one of the options
+//   - true_false: Answer, "true" or "false"
+//   - multi_select: Options and Answers, the correct options
+//   - ordering: Options in shuffled order and Answers, the same items in
+//     the correct order
+//   - fill_blank: Answer, the missing text, and Answers, other accepted
+//     spellings
+//   - short_answer: Answer, a model answer, Rubric and Keywords, the terms
+//     a correct answer mentions
 type QuizQuestion struct {
+	Type        string   `json:"type,omitempty"` // Empty for quizzes stored before question types: multiple_choice
 	Question    string   `json:"question"`
-	Options     []string `json:"options"`
-	Answer      string   `json:"answer"`
+	Options     []string `json:"options,omitempty"`
+	Answer      string   `json:"answer,omitempty"`
+	Answers     []string `json:"answers,omitempty"`
+	Rubric      string   `json:"rubric,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
 	Explanation string   `json:"explanation"`
 }
 
+// QuestionType returns the question's type, defaulting to multiple_choice
+func (q *QuizQuestion) QuestionType() string {
+	if q.Type == "" {
+		return QuestionMultipleChoice
+	}
+	return q.Type
+}
+
+// QuizPlayQuestion is a question without its answer and explanation,
+// served to clients that are about to play the quiz
+type QuizPlayQuestion struct {
+	Type     string   `json:"type"`
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// PlayQuestions strips answers and explanations from questions
+func PlayQuestions(questions []QuizQuestion) []QuizPlayQuestion {
+	play := make([]QuizPlayQuestion, len(questions))
+	for i, q := range questions {
+		play[i] = QuizPlayQuestion{
+			Type:     q.QuestionType(),
+			Question: q.Question,
+			Options:  q.Options,
+		}
+	}
+	return play
+}
+
 // QuizData represents the structure of quiz questions in JSONB
 type QuizData struct {
 	Quiz []QuizQuestion `json:"quiz"`
@@ -50,3 +171,58 @@ type QuizResponse struct {
 	CreatedAt time.Time      `json:"created_at"`
 }
 
+// QuizVersionResponse summarizes a quiz version in version listings
+type QuizVersionResponse struct {
+	ID             uuid.UUID `json:"id"`
+	Version        int       `json:"version"`
+	Status         string    `json:"status"`
+	Active         bool      `json:"active"`
+	Difficulty     string    `json:"difficulty"`
+	Language       string    `json:"language"`
+	QuestionsCount int       `json:"questions_count"`
+	AgeGroup       string    `json:"age_group,omitempty"`
+	Focus          []string  `json:"focus,omitempty"`
+	AIModel        string    `json:"ai_model"`
+	PromptVersion  string    `json:"prompt_version,omitempty"`
+	QualityScore   *int      `json:"quality_score,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ParseQuestions decodes the stored questions. Both the plain array format
+// and the nested {"quiz": [...]} format are accepted. Questions stored
+// without a type get multiple_choice.
+func (q *Quiz) ParseQuestions() ([]QuizQuestion, error) {
+	var questions []QuizQuestion
+	if err := json.Unmarshal(q.Questions, &questions); err != nil {
+		var quizData QuizData
+		if err2 := json.Unmarshal(q.Questions, &quizData); err2 != nil {
+			return nil, err2
+		}
+		questions = quizData.Quiz
+	}
+	for i := range questions {
+		questions[i].Type = questions[i].QuestionType()
+	}
+	return questions, nil
+}
+
+// ToVersionResponse converts Quiz model to QuizVersionResponse
+func (q *Quiz) ToVersionResponse(activeQuizID *uuid.UUID) *QuizVersionResponse {
+	questions, _ := q.ParseQuestions()
+
+	return &QuizVersionResponse{
+		ID:             q.ID,
+		Version:        q.Version,
+		Status:         q.Status,
+		Active:         activeQuizID != nil && *activeQuizID == q.ID,
+		Difficulty:     q.Difficulty,
+		Language:       q.Language,
+		QuestionsCount: len(questions),
+		AgeGroup:       q.AgeGroup,
+		Focus:          []string(q.Focus),
+		AIModel:        q.AIModel,
+		PromptVersion:  q.PromptVersion,
+		QualityScore:   q.QualityScore,
+		CreatedAt:      q.CreatedAt,
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RateLimitBucket is a token bucket stored in Postgres, used when
+// RATE_LIMIT_BACKEND=postgres
+type RateLimitBucket struct {
+	Key       string    `gorm:"primaryKey" json:"key"`
+	Tokens    float64   `gorm:"not null" json:"tokens"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// LLMUsage counts LLM calls per UTC day, shared by all replicas to enforce
+// the daily budget
+type LLMUsage struct {
+	Day       time.Time `gorm:"type:date;primaryKey" json:"day"`
+	Calls     int       `gorm:"not null;default:0" json:"calls"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
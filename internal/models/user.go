@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is an account known from a verified JWT. Users are created on their
+// first authenticated request; the token's issuer and subject identify them.
+type User struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Issuer     string    `gorm:"not null;default:'';uniqueIndex:idx_users_identity" json:"issuer,omitempty"`
+	Subject    string    `gorm:"not null;uniqueIndex:idx_users_identity" json:"subject"` // "sub" claim
+	Email      string    `json:"email,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Role       string    `gorm:"not null;default:'reader'" json:"role"` // "reader", "editor", "admin"
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (User) TableName() string {
+	return "users"
+}
+
+// User roles, from least to most privileged. Each role includes the
+// permissions of the roles before it.
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether the user has at least the given role
+func (u *User) HasRole(role string) bool {
+	return roleRanks[u.Role] >= roleRanks[role] && roleRanks[role] > 0
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/isbn"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Book editing errors
+var (
+	ErrBookNotFound       = errors.New("book not found")
+	ErrInvalidBookField   = errors.New("invalid book field")
+	ErrBookNotRefreshable = errors.New("book has no valid ISBN to refresh from")
+)
+
+// BookEdit is a manual correction of a book. Fields lists the editable
+// fields taken from Values; edited fields are locked unless listed in
+// Unlock. Lock and Unlock change locks without editing values.
+type BookEdit struct {
+	Values models.Book
+	Fields []string
+	Lock   []string
+	Unlock []string
+}
+
+// BookValidationError lists the problems of manually entered book data
+type BookValidationError struct {
+	Issues []string
+}
+
+// Error implements the error interface
+func (e *BookValidationError) Error() string {
+	return "invalid book data: " + strings.Join(e.Issues, "; ")
+}
+
+// NormalizeBookFields trims the given fields of manually entered book data
+// and validates them
+func NormalizeBookFields(book *models.Book, fields []string) error {
+	issues := []string{}
+	for _, field := range fields {
+		switch field {
+		case models.FieldTitle:
+			book.Title = strings.TrimSpace(book.Title)
+			if book.Title == "" {
+				issues = append(issues, "title must not be empty")
+			} else if utf8.RuneCountInString(book.Title) > 500 {
+				issues = append(issues, "title must be at most 500 characters")
+			}
+		case models.FieldAuthors:
+			book.Authors = trimList(book.Authors)
+		case models.FieldCategories:
+			book.Categories = trimList(book.Categories)
+		case models.FieldDescription:
+			book.Description = strings.TrimSpace(book.Description)
+		case models.FieldPublisher:
+			book.Publisher = strings.TrimSpace(book.Publisher)
+		case models.FieldPublishedDate:
+			book.PublishedDate = strings.TrimSpace(book.PublishedDate)
+			if book.PublishedDate != "" && !publishedDatePattern.MatchString(book.PublishedDate) {
+				issues = append(issues, "published_date must be YYYY, YYYY-MM or YYYY-MM-DD")
+			}
+		case models.FieldPageCount:
+			if book.PageCount < 0 {
+				issues = append(issues, "page_count must not be negative")
+			}
+		case models.FieldLanguage:
+			book.Language = strings.ToLower(strings.TrimSpace(book.Language))
+			if book.Language != "" && !languagePattern.MatchString(book.Language) {
+				issues = append(issues, "language must be a language code such as tr or en")
+			}
+		case models.FieldCoverURL, models.FieldThumbnailURL:
+			value := strings.TrimSpace(book.FieldValue(field).(string))
+			if value != "" {
+				if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+					issues = append(issues, field+" must be an http(s) URL")
+				}
+			}
+			if field == models.FieldCoverURL {
+				book.CoverURL = value
+			} else {
+				book.ThumbnailURL = value
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return &BookValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// Patterns for manually entered values
+var (
+	publishedDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+	languagePattern      = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+)
+
+// trimList trims every value and drops empty ones
+func trimList(values pq.StringArray) pq.StringArray {
+	result := pq.StringArray{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// NewManualBook builds an unsaved book from manually entered metadata for
+// a book no provider knows. Title and at least one author are required.
+// Every entered field is locked so later refreshes keep it.
+func NewManualBook(parsed isbn.ISBN, values models.Book, fields []string) (*models.Book, error) {
+	for _, field := range fields {
+		if !models.IsEditableBookField(field) {
+			return nil, fmt.Errorf("%w %q", ErrInvalidBookField, field)
+		}
+	}
+
+	book := values
+	issues := []string{}
+	if !slices.Contains(fields, models.FieldTitle) {
+		issues = append(issues, "title is required")
+	}
+	var validationErr *BookValidationError
+	if err := NormalizeBookFields(&book, fields); errors.As(err, &validationErr) {
+		issues = append(issues, validationErr.Issues...)
+	}
+	if len(book.Authors) == 0 {
+		issues = append(issues, "at least one author is required")
+	}
+	if len(issues) > 0 {
+		return nil, &BookValidationError{Issues: issues}
+	}
+
+	book.ID = uuid.New()
+	book.ISBN, book.ISBN13 = parsed.Stored()
+	book.QuizStatus = "pending"
+	book.DataSources = pq.StringArray{models.DataSourceManual}
+
+	provenance := models.BookProvenance{
+		models.FieldISBN:   {models.DataSourceManual},
+		models.FieldISBN13: {models.DataSourceManual},
+	}
+	locked := map[string]bool{}
+	for _, field := range fields {
+		provenance[field] = []string{models.DataSourceManual}
+		locked[field] = true
+	}
+	book.SetProvenance(provenance)
+	book.LockedFields = sortedFields(locked)
+	return &book, nil
+}
+
+// UpdateBook applies a manual edit. Edited fields are attributed to the
+// "manual" data source.
+func UpdateBook(bookID uuid.UUID, edit BookEdit) (*models.Book, error) {
+	for _, fields := range [][]string{edit.Fields, edit.Lock, edit.Unlock} {
+		for _, field := range fields {
+			if !models.IsEditableBookField(field) {
+				return nil, fmt.Errorf("%w %q", ErrInvalidBookField, field)
+			}
+		}
+	}
+
+	if err := NormalizeBookFields(&edit.Values, edit.Fields); err != nil {
+		return nil, err
+	}
+
+	var book models.Book
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBook(tx, bookID, &book); err != nil {
+			return err
+		}
+
+		provenance := book.GetProvenance()
+		locked := map[string]bool{}
+		for _, field := range book.LockedFields {
+			locked[field] = true
+		}
+
+		for _, field := range edit.Fields {
+			book.CopyField(field, &edit.Values)
+			provenance[field] = []string{models.DataSourceManual}
+			locked[field] = true
+		}
+		for _, field := range edit.Lock {
+			locked[field] = true
+		}
+		for _, field := range edit.Unlock {
+			delete(locked, field)
+		}
+
+		book.SetProvenance(provenance)
+		book.LockedFields = sortedFields(locked)
+		if len(edit.Fields) > 0 && !slices.Contains(book.DataSources, models.DataSourceManual) {
+			book.DataSources = append(book.DataSources, models.DataSourceManual)
+		}
+
+		columns := append([]string{"provenance", "locked_fields", "data_sources"}, edit.Fields...)
+		return tx.Model(&book).Select(columns).Updates(&book).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✏️ Book %s updated manually (fields: %v, locked: %v)", book.ID, edit.Fields, book.LockedFields)
+	return &book, nil
+}
+
+// BookDeletion reports what was removed with a book
+type BookDeletion struct {
+	Quizzes  int64 `json:"quizzes"`
+	Attempts int64 `json:"attempts"`
+	Jobs     int64 `json:"jobs"`
+}
+
+// DeleteBook deletes a book together with its quizzes, quiz attempts and
+// quiz jobs
+func DeleteBook(bookID uuid.UUID) (*BookDeletion, error) {
+	deletion := &BookDeletion{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var book models.Book
+		if err := lockBook(tx, bookID, &book); err != nil {
+			return err
+		}
+
+		jobs := tx.Model(&models.QuizJob{}).Select("id").Where("book_id = ?", bookID)
+		if err := tx.Where("job_id IN (?)", jobs).Delete(&models.QuizJobAttempt{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("book_id = ?", bookID).Delete(&models.QuizJob{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletion.Jobs = result.RowsAffected
+
+		result = tx.Where("book_id = ?", bookID).Delete(&models.QuizAttempt{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletion.Attempts = result.RowsAffected
+
+		result = tx.Where("book_id = ?", bookID).Delete(&models.Quiz{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletion.Quizzes = result.RowsAffected
+
+		return tx.Delete(&book).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🗑️ Book %s deleted (%d quizzes, %d attempts, %d jobs)", bookID, deletion.Quizzes, deletion.Attempts, deletion.Jobs)
+	return deletion, nil
+}
+
+// BookFieldChange is a field whose refreshed value differs from the stored one
+type BookFieldChange struct {
+	Field     string      `json:"field"`
+	Current   interface{} `json:"current"`
+	Refreshed interface{} `json:"refreshed"`
+	Sources   []string    `json:"sources,omitempty"` // Providers of the refreshed value
+	Locked    bool        `json:"locked"`            // Locked fields are never applied
+}
+
+// BookRefresh is the outcome of re-fetching a book from providers
+type BookRefresh struct {
+	Book    *models.Book
+	Changes []BookFieldChange
+	Applied bool
+}
+
+// RefreshBook searches the providers for the book's ISBN again and returns
+// the changed fields. With apply, unlocked changes are saved.
+func (s *BookMergerService) RefreshBook(ctx context.Context, bookID uuid.UUID, apply bool) (*BookRefresh, error) {
+	var book models.Book
+	if err := database.DB.Where("id = ?", bookID).First(&book).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookNotFound
+		}
+		return nil, err
+	}
+
+	query := book.ISBN13
+	if query == "" {
+		query = book.ISBN
+	}
+	if _, err := normalizeQuery(query, "isbn"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBookNotRefreshable, err)
+	}
+
+	refreshed, err := s.SearchBook(ctx, query, "isbn")
+	if err != nil {
+		return nil, err
+	}
+
+	changes := DiffBooks(&book, refreshed)
+	result := &BookRefresh{Book: &book, Changes: changes}
+	if !apply {
+		return result, nil
+	}
+
+	updated, err := applyBookRefresh(bookID, refreshed)
+	if err != nil {
+		return nil, err
+	}
+	result.Book = updated
+	result.Applied = true
+	return result, nil
+}
+
+// DiffBooks lists the editable fields that differ between current and refreshed
+func DiffBooks(current, refreshed *models.Book) []BookFieldChange {
+	provenance := refreshed.GetProvenance()
+
+	changes := []BookFieldChange{}
+	for _, field := range models.EditableBookFields {
+		currentValue, refreshedValue := current.FieldValue(field), refreshed.FieldValue(field)
+		if sameFieldValue(currentValue, refreshedValue) {
+			continue
+		}
+		changes = append(changes, BookFieldChange{
+			Field:     field,
+			Current:   currentValue,
+			Refreshed: refreshedValue,
+			Sources:   provenance[field],
+			Locked:    current.IsLocked(field),
+		})
+	}
+	return changes
+}
+
+// applyBookRefresh saves the refreshed values of unlocked fields. The diff
+// is recomputed under the row lock so concurrent edits aren't overwritten.
+func applyBookRefresh(bookID uuid.UUID, refreshed *models.Book) (*models.Book, error) {
+	var book models.Book
+	var applied []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBook(tx, bookID, &book); err != nil {
+			return err
+		}
+
+		provenance := book.GetProvenance()
+		refreshedProvenance := refreshed.GetProvenance()
+		for _, change := range DiffBooks(&book, refreshed) {
+			if change.Locked {
+				continue
+			}
+			book.CopyField(change.Field, refreshed)
+			if sources := refreshedProvenance[change.Field]; len(sources) > 0 {
+				provenance[change.Field] = sources
+			} else {
+				delete(provenance, change.Field)
+			}
+			applied = append(applied, change.Field)
+		}
+
+		book.SetProvenance(provenance)
+		book.SourceData = refreshed.SourceData
+		book.DataSources = refreshedDataSources(refreshed.DataSources, provenance)
+
+		columns := append([]string{"provenance", "source_data", "data_sources"}, applied...)
+		return tx.Model(&book).Select(columns).Updates(&book).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔄 Book %s refreshed from providers (fields: %v)", book.ID, applied)
+	return &book, nil
+}
+
+// refreshedDataSources returns the providers of a refresh, keeping
+// "manual" while any field still comes from a manual edit
+func refreshedDataSources(sources pq.StringArray, provenance models.BookProvenance) pq.StringArray {
+	result := append(pq.StringArray(nil), sources...)
+	for _, fieldSources := range provenance {
+		if slices.Contains(fieldSources, models.DataSourceManual) && !slices.Contains(result, models.DataSourceManual) {
+			result = append(result, models.DataSourceManual)
+		}
+	}
+	return result
+}
+
+// lockBook loads a book row FOR UPDATE
+func lockBook(tx *gorm.DB, bookID uuid.UUID, book *models.Book) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bookID).First(book).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrBookNotFound
+	}
+	return err
+}
+
+// sameFieldValue compares field values, treating nil and empty lists alike
+func sameFieldValue(a, b interface{}) bool {
+	if listA, ok := a.([]string); ok {
+		listB, _ := b.([]string)
+		return len(listA) == 0 && len(listB) == 0 || reflect.DeepEqual(listA, listB)
+	}
+	return a == b
+}
+
+// sortedFields returns the keys of a field set in EditableBookFields order
+func sortedFields(fields map[string]bool) pq.StringArray {
+	result := pq.StringArray{}
+	for _, field := range models.EditableBookFields {
+		if fields[field] {
+			result = append(result, field)
+		}
+	}
+	return result
+}
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// Catalog export formats
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatMARCXML = "marcxml"
+)
+
+// ExportFormats lists the supported catalog export formats
+var ExportFormats = []string{ExportFormatCSV, ExportFormatJSONL, ExportFormatMARCXML}
+
+// Catalog export errors
+var (
+	ErrInvalidExportFormat      = errors.New("invalid export format")
+	ErrExportQuizzesUnsupported = errors.New("quizzes can't be exported as MARCXML")
+)
+
+// exportBatchSize is the number of books loaded per query while exporting
+const exportBatchSize = 200
+
+// BookExportOptions controls a catalog export
+type BookExportOptions struct {
+	Format         string
+	Filter         BookFilter
+	IncludeQuizzes bool // Adds each book's active quiz (CSV and JSONL only)
+	QuizAnswers    bool // Keeps answers, explanations, keywords and rubrics in exported quizzes
+}
+
+// Validate checks the format and its options
+func (o BookExportOptions) Validate() error {
+	switch o.Format {
+	case ExportFormatCSV, ExportFormatJSONL:
+		return nil
+	case ExportFormatMARCXML:
+		if o.IncludeQuizzes {
+			return ErrExportQuizzesUnsupported
+		}
+		return nil
+	}
+	return fmt.Errorf("%w %q (expected one of: %s)", ErrInvalidExportFormat, o.Format, strings.Join(ExportFormats, ", "))
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatJSONL:
+		return "application/x-ndjson"
+	case ExportFormatMARCXML:
+		return "application/marcxml+xml; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// ExportFileName returns the suggested file name of an export
+func ExportFileName(format string, at time.Time) string {
+	extension := format
+	if format == ExportFormatMARCXML {
+		extension = "xml"
+	}
+	return fmt.Sprintf("bookwise-books-%s.%s", at.UTC().Format("20060102-150405"), extension)
+}
+
+// ExportQuiz is a book's active quiz as written to exports
+type ExportQuiz struct {
+	ID        uuid.UUID   `json:"id"`
+	Version   int         `json:"version"`
+	AIModel   string      `json:"ai_model"`
+	Questions interface{} `json:"questions"` // []models.QuizQuestion with answers, []models.QuizPlayQuestion without
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// bookExportWriter writes books in one export format
+type bookExportWriter interface {
+	begin() error
+	write(book *models.Book, quiz *ExportQuiz) error
+	flush() error
+	end() error
+}
+
+// ExportBooks streams the books matching the options to w, oldest first,
+// loading them in batches. w is flushed after every batch when it has a
+// Flush method (e.g. an HTTP response). It returns the number of books
+// written; on error the output is truncated.
+func ExportBooks(ctx context.Context, w io.Writer, opts BookExportOptions) (int, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+
+	var writer bookExportWriter
+	switch opts.Format {
+	case ExportFormatCSV:
+		writer = &csvBookWriter{w: csv.NewWriter(w), quizzes: opts.IncludeQuizzes}
+	case ExportFormatJSONL:
+		writer = &jsonlBookWriter{encoder: json.NewEncoder(w)}
+	case ExportFormatMARCXML:
+		writer = &marcBookWriter{w: w, encoder: xml.NewEncoder(w)}
+	}
+
+	flush := func() error {
+		if err := writer.flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := writer.begin(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	sort := BookSort{Field: "created_at"}
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		page, err := ListBooks(opts.Filter, sort, cursor, 0, exportBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		var quizzes map[uuid.UUID]*ExportQuiz
+		if opts.IncludeQuizzes {
+			if quizzes, err = loadExportQuizzes(page.Books, opts.QuizAnswers); err != nil {
+				return count, err
+			}
+		}
+
+		for i := range page.Books {
+			book := &page.Books[i]
+			var quiz *ExportQuiz
+			if book.QuizID != nil {
+				quiz = quizzes[*book.QuizID]
+			}
+			if err := writer.write(book, quiz); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+		if err := flush(); err != nil {
+			return count, err
+		}
+	}
+
+	if err := writer.end(); err != nil {
+		return count, err
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+
+	log.Printf("📤 Exported %d books as %s", count, opts.Format)
+	return count, nil
+}
+
+// loadExportQuizzes loads the active quizzes of books, keyed by quiz ID.
+// Without answers only the play view of the questions is exported.
+func loadExportQuizzes(books []models.Book, answers bool) (map[uuid.UUID]*ExportQuiz, error) {
+	ids := make([]uuid.UUID, 0, len(books))
+	for _, book := range books {
+		if book.QuizID != nil {
+			ids = append(ids, *book.QuizID)
+		}
+	}
+	quizzes := make(map[uuid.UUID]*ExportQuiz, len(ids))
+	if len(ids) == 0 {
+		return quizzes, nil
+	}
+
+	var stored []models.Quiz
+	if err := database.DB.Where("id IN ? AND status = ?", ids, "completed").Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load quizzes: %w", err)
+	}
+	for i := range stored {
+		questions, err := stored[i].ParseQuestions()
+		if err != nil {
+			log.Printf("⚠️ Skipping unreadable quiz %s in export: %v", stored[i].ID, err)
+			continue
+		}
+		quiz := &ExportQuiz{
+			ID:        stored[i].ID,
+			Version:   stored[i].Version,
+			AIModel:   stored[i].AIModel,
+			Questions: models.PlayQuestions(questions),
+			CreatedAt: stored[i].CreatedAt,
+		}
+		if answers {
+			quiz.Questions = questions
+		}
+		quizzes[stored[i].ID] = quiz
+	}
+	return quizzes, nil
+}
+
+// csvListSeparator joins list values (authors, categories) in one CSV cell
+const csvListSeparator = "; "
+
+// csvBookWriter writes one row per book. Quizzes add their ID, version
+// and questions as a JSON array.
+type csvBookWriter struct {
+	w       *csv.Writer
+	quizzes bool
+}
+
+func (cw *csvBookWriter) begin() error {
+	header := []string{
+		"id", "title", "authors", "isbn", "isbn13", "description", "publisher",
+		"published_date", "page_count", "categories", "language", "cover_url",
+		"thumbnail_url", "data_sources", "quiz_status", "created_at", "updated_at",
+	}
+	if cw.quizzes {
+		header = append(header, "quiz_id", "quiz_version", "quiz_questions")
+	}
+	return cw.w.Write(header)
+}
+
+func (cw *csvBookWriter) write(book *models.Book, quiz *ExportQuiz) error {
+	pageCount := ""
+	if book.PageCount > 0 {
+		pageCount = strconv.Itoa(book.PageCount)
+	}
+
+	row := []string{
+		book.ID.String(),
+		book.Title,
+		strings.Join(book.Authors, csvListSeparator),
+		book.ISBN,
+		book.ISBN13,
+		book.Description,
+		book.Publisher,
+		book.PublishedDate,
+		pageCount,
+		strings.Join(book.Categories, csvListSeparator),
+		book.Language,
+		book.CoverURL,
+		book.ThumbnailURL,
+		strings.Join(book.DataSources, csvListSeparator),
+		book.QuizStatus,
+		book.CreatedAt.UTC().Format(time.RFC3339),
+		book.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if cw.quizzes {
+		if quiz != nil {
+			questions, err := json.Marshal(quiz.Questions)
+			if err != nil {
+				return err
+			}
+			row = append(row, quiz.ID.String(), strconv.Itoa(quiz.Version), string(questions))
+		} else {
+			row = append(row, "", "", "")
+		}
+	}
+	return cw.w.Write(row)
+}
+
+func (cw *csvBookWriter) flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvBookWriter) end() error { return nil }
+
+// jsonlBookWriter writes one JSON object per line, shaped like the book
+// API responses
+type jsonlBookWriter struct {
+	encoder *json.Encoder
+}
+
+// exportBookLine is a JSONL export line
+type exportBookLine struct {
+	*models.BookResponse
+	UpdatedAt time.Time   `json:"updated_at"`
+	Quiz      *ExportQuiz `json:"quiz,omitempty"`
+}
+
+func (jw *jsonlBookWriter) begin() error { return nil }
+
+func (jw *jsonlBookWriter) write(book *models.Book, quiz *ExportQuiz) error {
+	return jw.encoder.Encode(exportBookLine{
+		BookResponse: book.ToResponse(),
+		UpdatedAt:    book.UpdatedAt,
+		Quiz:         quiz,
+	})
+}
+
+func (jw *jsonlBookWriter) flush() error { return nil }
+
+func (jw *jsonlBookWriter) end() error { return nil }
+
+// marcBookWriter writes a MARC 21 XML (MARCXML) collection with one
+// bibliographic record per book
+type marcBookWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+}
+
+// marcRecord is a MARCXML record
+type marcRecord struct {
+	XMLName       xml.Name           `xml:"record"`
+	Leader        string             `xml:"leader"`
+	ControlFields []marcControlField `xml:"controlfield"`
+	DataFields    []marcDataField    `xml:"datafield"`
+}
+
+type marcControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []marcSubfield `xml:"subfield"`
+}
+
+type marcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// marcLeader describes a new language material (book) record; lengths
+// and addresses are computed by the importing system
+const marcLeader = "00000nam a2200000 i 4500"
+
+// marcLanguages maps ISO 639-1 codes to MARC language codes
+var marcLanguages = map[string]string{
+	"ar": "ara", "de": "ger", "en": "eng", "es": "spa", "fa": "per",
+	"fr": "fre", "it": "ita", "ja": "jpn", "ko": "kor", "nl": "dut",
+	"pt": "por", "ru": "rus", "tr": "tur", "zh": "chi",
+}
+
+func (mw *marcBookWriter) begin() error {
+	_, err := io.WriteString(mw.w, xml.Header+`<collection xmlns="http://www.loc.gov/MARC21/slim">`+"\n")
+	return err
+}
+
+func (mw *marcBookWriter) write(book *models.Book, _ *ExportQuiz) error {
+	if err := mw.encoder.Encode(marcBookRecord(book)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(mw.w, "\n")
+	return err
+}
+
+func (mw *marcBookWriter) flush() error { return nil }
+
+func (mw *marcBookWriter) end() error {
+	_, err := io.WriteString(mw.w, "</collection>\n")
+	return err
+}
+
+// marcBookRecord maps a book to a MARC bibliographic record
+func marcBookRecord(book *models.Book) marcRecord {
+	language := marcLanguages[strings.ToLower(strings.SplitN(book.Language, "-", 2)[0])]
+
+	record := marcRecord{
+		Leader: marcLeader,
+		ControlFields: []marcControlField{
+			{Tag: "001", Value: book.ID.String()},
+			{Tag: "003", Value: "Bookwise"},
+			{Tag: "005", Value: book.UpdatedAt.UTC().Format("20060102150405") + ".0"},
+			{Tag: "008", Value: marcFixedField(book, language)},
+		},
+	}
+
+	field := func(tag, ind1, ind2 string, subfields ...marcSubfield) {
+		var kept []marcSubfield
+		for _, subfield := range subfields {
+			if subfield.Value != "" {
+				kept = append(kept, subfield)
+			}
+		}
+		if len(kept) > 0 {
+			record.DataFields = append(record.DataFields, marcDataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: kept})
+		}
+	}
+
+	isbn13 := book.ISBN13
+	if isbn13 == "" && len(book.ISBN) == 13 {
+		isbn13 = book.ISBN
+	}
+	field("020", " ", " ", marcSubfield{Code: "a", Value: isbn13})
+	if book.ISBN != isbn13 {
+		field("020", " ", " ", marcSubfield{Code: "a", Value: book.ISBN})
+	}
+	field("041", "0", " ", marcSubfield{Code: "a", Value: language})
+
+	authors := []string(book.Authors)
+	if len(authors) > 0 {
+		field("100", "1", " ", marcSubfield{Code: "a", Value: authors[0]}, marcSubfield{Code: "e", Value: "author."})
+	}
+	titleInd1 := "0"
+	if len(authors) > 0 {
+		titleInd1 = "1" // Title added entry, the main entry is the author
+	}
+	field("245", titleInd1, "0", marcSubfield{Code: "a", Value: book.Title})
+	field("264", " ", "1",
+		marcSubfield{Code: "b", Value: book.Publisher},
+		marcSubfield{Code: "c", Value: book.PublishedDate},
+	)
+	if book.PageCount > 0 {
+		field("300", " ", " ", marcSubfield{Code: "a", Value: fmt.Sprintf("%d pages", book.PageCount)})
+	}
+	field("520", " ", " ", marcSubfield{Code: "a", Value: book.Description})
+	for _, category := range book.Categories {
+		field("650", " ", "4", marcSubfield{Code: "a", Value: category})
+	}
+	if len(authors) > 1 {
+		for _, author := range authors[1:] {
+			field("700", "1", " ", marcSubfield{Code: "a", Value: author}, marcSubfield{Code: "e", Value: "author."})
+		}
+	}
+
+	coverURL := book.CoverURL
+	if coverURL == "" {
+		coverURL = book.ThumbnailURL
+	}
+	field("856", "4", "2", marcSubfield{Code: "3", Value: "Cover image"}, marcSubfield{Code: "u", Value: coverURL})
+
+	return record
+}
+
+// marcFixedField builds the 40-character 008 field: date entered,
+// publication year and language; other positions are left uncoded
+func marcFixedField(book *models.Book, language string) string {
+	dateType, year := "n", "uuuu" // Unknown date
+	if len(book.PublishedDate) >= 4 {
+		if _, err := strconv.Atoi(book.PublishedDate[:4]); err == nil {
+			dateType, year = "s", book.PublishedDate[:4]
+		}
+	}
+	if language == "" {
+		language = "und"
+	}
+
+	return book.CreatedAt.UTC().Format("060102") + // 00-05 date entered
+		dateType + year + "    " + // 06-14 date type, date 1, date 2
+		"xx " + // 15-17 place of publication unknown
+		strings.Repeat("|", 17) + // 18-34 book material, not coded
+		language + // 35-37
+		" d" // 38-39 not modified, other cataloging source
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/isbn"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bulk import outcomes of a single ISBN
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusExisting  = "existing"
+	ImportStatusNotFound  = "not_found"
+	ImportStatusInvalid   = "invalid"
+	ImportStatusDuplicate = "duplicate" // Same ISBN appeared earlier in the list
+	ImportStatusFailed    = "failed"    // Unexpected error, safe to retry
+)
+
+// ImportStatuses lists the bulk import outcomes in report order
+var ImportStatuses = []string{
+	ImportStatusCreated,
+	ImportStatusExisting,
+	ImportStatusNotFound,
+	ImportStatusInvalid,
+	ImportStatusDuplicate,
+	ImportStatusFailed,
+}
+
+// ImportResult is the outcome of importing a single ISBN
+type ImportResult struct {
+	Input  string     `json:"input"`
+	ISBN   string     `json:"isbn,omitempty"` // Normalized ISBN-13
+	Status string     `json:"status"`
+	BookID *uuid.UUID `json:"book_id,omitempty"`
+	Title  string     `json:"title,omitempty"`
+	JobID  *uuid.UUID `json:"job_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// BookImportOptions controls a bulk ISBN import
+type BookImportOptions struct {
+	// Concurrency bounds how many ISBNs are looked up at once,
+	// 0 uses IMPORT_CONCURRENCY
+	Concurrency int
+
+	// EnqueueQuiz, when set, is called for imported books whose quiz is
+	// still pending
+	EnqueueQuiz func(bookID uuid.UUID) (*models.QuizJob, error)
+}
+
+// ImportISBNs stores the books of the given ISBNs, fetching metadata from
+// the providers with bounded concurrency. onResult (optional) is called
+// once per processed ISBN, never concurrently. Results are returned in
+// input order; when ctx is cancelled, ISBNs not yet started are left out.
+func (s *BookMergerService) ImportISBNs(ctx context.Context, inputs []string, opts BookImportOptions, onResult func(ImportResult)) []ImportResult {
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = s.importWorkers
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	log.Printf("📥 Importing %d ISBNs (concurrency: %d)", len(inputs), concurrency)
+
+	results := make([]ImportResult, len(inputs))
+	processed := make([]bool, len(inputs))
+	var mu sync.Mutex
+	report := func(i int, result ImportResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = result
+		processed[i] = true
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	// Invalid and repeated ISBNs are answered without a lookup
+	pending := make(chan int)
+	seen := map[string]bool{}
+	lookups := make([]isbn.ISBN, len(inputs))
+	go func() {
+		defer close(pending)
+		for i, input := range inputs {
+			if ctx.Err() != nil {
+				return
+			}
+			parsed, err := isbn.Parse(input)
+			if err != nil {
+				report(i, ImportResult{Input: input, Status: ImportStatusInvalid, Error: err.Error()})
+				continue
+			}
+			if seen[parsed.ISBN13] {
+				report(i, ImportResult{Input: input, ISBN: parsed.ISBN13, Status: ImportStatusDuplicate})
+				continue
+			}
+			seen[parsed.ISBN13] = true
+			lookups[i] = parsed
+
+			select {
+			case pending <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				report(i, s.importISBN(ctx, inputs[i], lookups[i], opts))
+			}
+		}()
+	}
+	wg.Wait()
+
+	ordered := make([]ImportResult, 0, len(inputs))
+	for i, result := range results {
+		if processed[i] {
+			ordered = append(ordered, result)
+		}
+	}
+
+	summary := SummarizeImport(ordered)
+	log.Printf("✅ Import finished: %d created, %d existing, %d not found, %d invalid, %d failed",
+		summary[ImportStatusCreated], summary[ImportStatusExisting], summary[ImportStatusNotFound],
+		summary[ImportStatusInvalid], summary[ImportStatusFailed])
+
+	return ordered
+}
+
+// ImportMaxBatch returns the max number of ISBNs accepted by a single
+// bulk import request (0 = unlimited)
+func (s *BookMergerService) ImportMaxBatch() int {
+	return s.importMaxBatch
+}
+
+// importISBN imports a single parsed ISBN
+func (s *BookMergerService) importISBN(ctx context.Context, input string, parsed isbn.ISBN, opts BookImportOptions) ImportResult {
+	result := ImportResult{Input: input, ISBN: parsed.ISBN13}
+
+	book, created, err := s.ImportBook(ctx, parsed)
+	switch {
+	case errors.Is(err, ErrBookNotInSources):
+		result.Status = ImportStatusNotFound
+		return result
+	case err != nil:
+		log.Printf("❌ Failed to import ISBN %s: %v", parsed.ISBN13, err)
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = ImportStatusExisting
+	if created {
+		result.Status = ImportStatusCreated
+	}
+	result.BookID = &book.ID
+	result.Title = book.Title
+
+	if opts.EnqueueQuiz != nil && book.QuizStatus == "pending" {
+		job, err := opts.EnqueueQuiz(book.ID)
+		if err != nil {
+			// The book is stored; the quiz can still be requested later
+			log.Printf("❌ Failed to enqueue quiz generation for %s: %v", book.ID, err)
+			result.Error = err.Error()
+		} else {
+			result.JobID = &job.ID
+		}
+	}
+
+	return result
+}
+
+// ImportBook returns the stored book with the given ISBN, or fetches it
+// from the providers and stores it. created reports whether it was new.
+func (s *BookMergerService) ImportBook(ctx context.Context, parsed isbn.ISBN) (*models.Book, bool, error) {
+	existing, err := findStoredBook(parsed)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to look up book: %w", err)
+	}
+
+	book, err := s.SearchBook(ctx, parsed.ISBN13, "isbn")
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Another request may have stored the same book in the meantime
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(book)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to save book: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		existing, err := findStoredBook(parsed)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to load existing book: %w", err)
+		}
+		return existing, false, nil
+	}
+
+	log.Printf("✅ Book saved to database: %s (ID: %s)", book.Title, book.ID)
+	return book, true, nil
+}
+
+// findStoredBook looks up a stored book by any form of a parsed ISBN
+func findStoredBook(parsed isbn.ISBN) (*models.Book, error) {
+	var book models.Book
+	variants := parsed.Variants()
+	if err := database.DB.Where("isbn IN ? OR isbn13 IN ?", variants, variants).First(&book).Error; err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// SummarizeImport counts import results by status
+func SummarizeImport(results []ImportResult) map[string]int {
+	summary := make(map[string]int, len(ImportStatuses))
+	for _, status := range ImportStatuses {
+		summary[status] = 0
+	}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+	return summary
+}
+
+// ParseISBNList reads ISBNs from CSV or plain text (one per line). If the
+// first row has an "isbn" column, that column is used, otherwise the first
+// one. Blank lines and lines starting with # are skipped.
+func ParseISBNList(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	isbns := []string{}
+	column := 0
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if row == 0 {
+			if header := isbnColumn(record); header >= 0 {
+				column = header
+				continue
+			}
+		}
+
+		if column >= len(record) {
+			continue
+		}
+		if value := strings.TrimSpace(record[column]); value != "" {
+			isbns = append(isbns, value)
+		}
+	}
+
+	return isbns, nil
+}
+
+// isbnColumn returns the index of the "isbn" header column, or -1
+func isbnColumn(record []string) int {
+	for i, value := range record {
+		value = strings.TrimPrefix(value, "\ufeff") // Byte order mark from spreadsheet exports
+		if strings.EqualFold(strings.TrimSpace(value), "isbn") {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Book listing errors
+var (
+	ErrInvalidBookSort   = errors.New("invalid sort field")
+	ErrInvalidBookCursor = errors.New("invalid cursor")
+)
+
+// BookQuizStatuses are the valid values of Book.QuizStatus
+var BookQuizStatuses = []string{"pending", "generating", "completed", "failed"}
+
+// BookFilter narrows a book listing. Zero values don't filter.
+type BookFilter struct {
+	Author        string // Case-insensitive substring of any author
+	Category      string // Case-insensitive substring of any category
+	Language      string // Exact language code, case-insensitive
+	QuizStatus    string
+	Publisher     string // Case-insensitive substring
+	PublishedFrom int    // Earliest publication year
+	PublishedTo   int    // Latest publication year
+	HasCover      *bool
+}
+
+// Apply adds the filter conditions to a books query
+func (f BookFilter) Apply(db *gorm.DB) *gorm.DB {
+	if f.Author != "" {
+		db = db.Where("EXISTS (SELECT 1 FROM unnest(books.authors) AS author WHERE author ILIKE ?)", likePattern(f.Author))
+	}
+	if f.Category != "" {
+		db = db.Where("EXISTS (SELECT 1 FROM unnest(books.categories) AS category WHERE category ILIKE ?)", likePattern(f.Category))
+	}
+	if f.Language != "" {
+		db = db.Where("LOWER(books.language) = LOWER(?)", f.Language)
+	}
+	if f.QuizStatus != "" {
+		db = db.Where("books.quiz_status = ?", f.QuizStatus)
+	}
+	if f.Publisher != "" {
+		db = db.Where("books.publisher ILIKE ?", likePattern(f.Publisher))
+	}
+
+	// published_date is free text from providers ("2009", "2009-07-31");
+	// the year is its leading four digits
+	const publishedYear = "CAST(SUBSTRING(books.published_date FROM '^[0-9]{4}') AS INTEGER)"
+	if f.PublishedFrom > 0 {
+		db = db.Where(publishedYear+" >= ?", f.PublishedFrom)
+	}
+	if f.PublishedTo > 0 {
+		db = db.Where(publishedYear+" <= ?", f.PublishedTo)
+	}
+
+	if f.HasCover != nil {
+		if *f.HasCover {
+			db = db.Where("COALESCE(books.cover_url, '') <> '' OR COALESCE(books.thumbnail_url, '') <> ''")
+		} else {
+			db = db.Where("COALESCE(books.cover_url, '') = '' AND COALESCE(books.thumbnail_url, '') = ''")
+		}
+	}
+	return db
+}
+
+// likePattern escapes LIKE wildcards and matches value anywhere
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
+
+// bookSortFields maps sortable field names to the type of their cursor value
+var bookSortFields = map[string]string{
+	"created_at":     "time",
+	"updated_at":     "time",
+	"title":          "string",
+	"published_date": "string",
+	"page_count":     "int",
+}
+
+// BookSortFields returns the sortable field names
+func BookSortFields() []string {
+	return []string{"created_at", "updated_at", "title", "published_date", "page_count"}
+}
+
+// BookSort orders a book listing. Ties are broken by ID so the order is
+// stable across pages.
+type BookSort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseBookSort parses a sort field and an "asc"/"desc" order. A leading
+// "-" on the field also selects descending order. The default is newest
+// first.
+func ParseBookSort(field, order string) (BookSort, error) {
+	sort := BookSort{Field: "created_at", Desc: true}
+	if strings.HasPrefix(field, "-") {
+		field = strings.TrimPrefix(field, "-")
+		order = "desc"
+	}
+	if field != "" {
+		if _, ok := bookSortFields[field]; !ok {
+			return sort, fmt.Errorf("%w %q (expected one of: %s)", ErrInvalidBookSort, field, strings.Join(BookSortFields(), ", "))
+		}
+		sort.Field = field
+		sort.Desc = false
+	}
+
+	switch strings.ToLower(order) {
+	case "":
+	case "asc":
+		sort.Desc = false
+	case "desc":
+		sort.Desc = true
+	default:
+		return sort, fmt.Errorf("%w: order must be asc or desc", ErrInvalidBookSort)
+	}
+	return sort, nil
+}
+
+// direction returns the SQL sort direction
+func (s BookSort) direction() string {
+	if s.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// bookCursor is the decoded form of an opaque pagination cursor: the sort
+// it belongs to and the sort value and ID of the last book returned
+type bookCursor struct {
+	Field string    `json:"f"`
+	Desc  bool      `json:"d"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// encodeBookCursor returns the cursor pointing after book
+func encodeBookCursor(sort BookSort, book *models.Book) string {
+	cursor := bookCursor{Field: sort.Field, Desc: sort.Desc, ID: book.ID}
+	switch sort.Field {
+	case "created_at":
+		cursor.Value = book.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = book.UpdatedAt.Format(time.RFC3339Nano)
+	case "title":
+		cursor.Value = book.Title
+	case "published_date":
+		cursor.Value = book.PublishedDate
+	case "page_count":
+		cursor.Value = strconv.Itoa(book.PageCount)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeBookCursor parses a cursor and returns its typed sort value. The
+// cursor must have been issued for the same sort.
+func decodeBookCursor(sort BookSort, encoded string) (*bookCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrInvalidBookCursor
+	}
+	var cursor bookCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, nil, ErrInvalidBookCursor
+	}
+	if cursor.Field != sort.Field || cursor.Desc != sort.Desc {
+		return nil, nil, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidBookCursor)
+	}
+
+	switch bookSortFields[cursor.Field] {
+	case "time":
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, nil, ErrInvalidBookCursor
+		}
+		return &cursor, value, nil
+	case "int":
+		value, err := strconv.Atoi(cursor.Value)
+		if err != nil {
+			return nil, nil, ErrInvalidBookCursor
+		}
+		return &cursor, value, nil
+	default:
+		return &cursor, cursor.Value, nil
+	}
+}
+
+// BookPage is one page of a book listing
+type BookPage struct {
+	Books      []models.Book
+	NextCursor string // Empty on the last page
+}
+
+// ListBooks returns up to limit books matching filter in sort order.
+// Pages continue after cursor when it is set (keyset pagination), otherwise
+// start at offset.
+func ListBooks(filter BookFilter, sort BookSort, cursor string, offset, limit int) (*BookPage, error) {
+	column := "books." + sort.Field
+	query := filter.Apply(database.DB.Model(&models.Book{}))
+
+	if cursor != "" {
+		decoded, value, err := decodeBookCursor(sort, cursor)
+		if err != nil {
+			return nil, err
+		}
+		comparison := ">"
+		if sort.Desc {
+			comparison = "<"
+		}
+		query = query.Where(fmt.Sprintf("(%s, books.id) %s (?, ?)", column, comparison), value, decoded.ID)
+	} else if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	// Fetch one extra row to tell whether another page exists
+	var books []models.Book
+	err := query.
+		Order(fmt.Sprintf("%s %s, books.id %s", column, sort.direction(), sort.direction())).
+		Limit(limit + 1).
+		Find(&books).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list books: %w", err)
+	}
+
+	page := &BookPage{Books: books}
+	if len(books) > limit {
+		page.Books = books[:limit]
+		page.NextCursor = encodeBookCursor(sort, &page.Books[limit-1])
+	}
+	return page, nil
+}
+
+// CountBooks returns the number of books matching filter
+func CountBooks(filter BookFilter) (int64, error) {
+	var total int64
+	err := filter.Apply(database.DB.Model(&models.Book{})).Count(&total).Error
+	return total, err
+}
@@ -1,139 +1,164 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/isbn"
 	"github.com/bookwise/api/internal/models"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"gorm.io/datatypes"
 )
 
+// ErrBookNotInSources is returned by SearchBook when no provider knows the book
+var ErrBookNotInSources = errors.New("book not found in any source")
+
 // BookMergerService handles merging book data from multiple sources
 type BookMergerService struct {
-	googleBooks *GoogleBooksService
-	openLibrary *OpenLibraryService
+	providers        []BookProvider
+	searchTimeout    time.Duration
+	providerTimeout  time.Duration
+	providerTimeouts map[string]time.Duration
+	policy           *MergePolicy
+	cache            SearchCache // nil disables caching
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+	localMinResults  int
+	localMinRank     float64
+	importWorkers    int
+	importMaxBatch   int
 }
 
-// NewBookMergerService creates a new book merger service
-func NewBookMergerService(googleBooksAPIKey string) *BookMergerService {
-	return &BookMergerService{
-		googleBooks: NewGoogleBooksService(googleBooksAPIKey),
-		openLibrary: NewOpenLibraryService(),
+// NewBookMergerService creates a new book merger service.
+// Providers are queried concurrently; earlier providers have merge priority.
+// Provider results are cached in cache unless it is nil.
+func NewBookMergerService(providers []BookProvider, cache SearchCache, cfg *config.Config) (*BookMergerService, error) {
+	policy, err := NewMergePolicy(cfg.Merge)
+	if err != nil {
+		return nil, err
 	}
+
+	return &BookMergerService{
+		providers:        providers,
+		searchTimeout:    cfg.APIs.SearchTimeout,
+		providerTimeout:  cfg.APIs.ProviderTimeout,
+		providerTimeouts: cfg.APIs.ProviderTimeouts,
+		policy:           policy,
+		cache:            cache,
+		cacheTTL:         cfg.SearchCache.TTL,
+		negativeCacheTTL: cfg.SearchCache.NegativeTTL,
+		localMinResults:  cfg.LocalSearch.MinResults,
+		localMinRank:     cfg.LocalSearch.MinRank,
+		importWorkers:    cfg.Import.Concurrency,
+		importMaxBatch:   cfg.Import.MaxBatch,
+	}, nil
 }
 
-// SearchBook searches for a book using hybrid sources (returns single result)
-func (s *BookMergerService) SearchBook(query, searchType string) (*models.Book, error) {
-	var googleData, openLibraryData *BookData
-	var err error
-	
-	sources := []string{}
+// Providers returns the configured providers in priority order
+func (s *BookMergerService) Providers() []BookProvider {
+	return s.providers
+}
 
-	// Try Google Books first
-	log.Printf("🔍 Searching Google Books for: %s (type: %s)", query, searchType)
-	switch searchType {
-	case "isbn":
-		googleData, err = s.googleBooks.SearchByISBN(query)
-	case "title":
-		googleData, err = s.googleBooks.SearchByTitle(query)
-	case "author":
-		googleData, err = s.googleBooks.SearchByAuthor(query)
-	default:
-		return nil, fmt.Errorf("invalid search type: %s", searchType)
-	}
+// providerResult holds the outcome of a single provider call during fan-out
+type providerResult struct {
+	provider string
+	books    []*BookData
+	err      error
+	done     bool
+	cached   bool
+	elapsed  time.Duration
+}
 
-	if err == nil && googleData != nil {
-		sources = append(sources, "google_books")
-		log.Println("✅ Found in Google Books")
-	} else {
-		log.Printf("⚠️ Google Books: %v", err)
+// SearchBook searches for a book using hybrid sources (returns single result).
+// ISBN queries are validated and normalized; invalid ones return isbn.ErrInvalid.
+func (s *BookMergerService) SearchBook(ctx context.Context, query, searchType string) (*models.Book, error) {
+	query, err := normalizeQuery(query, searchType)
+	if err != nil {
+		return nil, err
 	}
 
-	// Try Open Library
-	log.Printf("🔍 Searching Open Library for: %s (type: %s)", query, searchType)
-	switch searchType {
-	case "isbn":
-		openLibraryData, err = s.openLibrary.SearchByISBN(query)
-	case "title":
-		openLibraryData, err = s.openLibrary.SearchByTitle(query)
-	case "author":
-		openLibraryData, err = s.openLibrary.SearchByAuthor(query)
-	}
+	log.Printf("🔍 Searching %d providers for: %s (type: %s)", len(s.providers), query, searchType)
 
-	if err == nil && openLibraryData != nil {
-		sources = append(sources, "open_library")
-		log.Println("✅ Found in Open Library")
-	} else {
-		log.Printf("⚠️ Open Library: %v", err)
+	outcomes := s.fanOut(ctx, func(provider string) string {
+		return searchCacheKey(provider, searchType, "single", query)
+	}, func(ctx context.Context, provider BookProvider) ([]*BookData, error) {
+		data, err := searchSingle(ctx, provider, query, searchType)
+		if err != nil || data == nil {
+			return nil, err
+		}
+		return []*BookData{data}, nil
+	})
+
+	results := make([]*BookData, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		if len(outcome.books) > 0 {
+			results = append(results, outcome.books[0])
+		}
 	}
 
-	// If no data found from either source
-	if googleData == nil && openLibraryData == nil {
-		return nil, fmt.Errorf("book not found in any source")
+	// If no data found from any source
+	if len(results) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("book search aborted: %w", err)
+		}
+		return nil, ErrBookNotInSources
 	}
 
 	// Merge the data
 	log.Println("🔄 Merging book data from sources...")
-	mergedBook := s.mergeBookData(googleData, openLibraryData, sources)
+	mergedBook := s.mergeBookData(results)
 	
 	return mergedBook, nil
 }
 
 // SearchBooks searches for books and returns multiple results
-func (s *BookMergerService) SearchBooks(query, searchType string, maxResults int) ([]*BookData, error) {
+func (s *BookMergerService) SearchBooks(ctx context.Context, query, searchType string, maxResults int) ([]*BookData, error) {
+	books, _, err := s.SearchBooksCached(ctx, query, searchType, maxResults)
+	return books, err
+}
+
+// SearchBooksCached is SearchBooks that also reports whether every
+// provider answer came from the search cache
+func (s *BookMergerService) SearchBooksCached(ctx context.Context, query, searchType string, maxResults int) ([]*BookData, bool, error) {
 	if maxResults <= 0 {
 		maxResults = 10
 	}
 
-	var googleBooks, openLibraryBooks []*BookData
-	var err error
-
-	// Try Google Books first
-	log.Printf("🔍 Searching Google Books for: %s (type: %s)", query, searchType)
-	switch searchType {
-	case "isbn":
-		googleBooks, err = s.googleBooks.SearchMultipleByISBN(query, maxResults)
-	case "title":
-		googleBooks, err = s.googleBooks.SearchMultipleByTitle(query, maxResults)
-	case "author":
-		googleBooks, err = s.googleBooks.SearchMultipleByAuthor(query, maxResults)
-	default:
-		return nil, fmt.Errorf("invalid search type: %s", searchType)
+	query, err := normalizeQuery(query, searchType)
+	if err != nil {
+		return nil, false, err
 	}
 
-	if err == nil && len(googleBooks) > 0 {
-		log.Printf("✅ Found %d books in Google Books", len(googleBooks))
-	} else {
-		log.Printf("⚠️ Google Books: %v", err)
-	}
+	log.Printf("🔍 Searching %d providers for: %s (type: %s)", len(s.providers), query, searchType)
 
-	// Try Open Library
-	log.Printf("🔍 Searching Open Library for: %s (type: %s)", query, searchType)
-	switch searchType {
-	case "isbn":
-		openLibraryBooks, err = s.openLibrary.SearchMultipleByISBN(query, maxResults)
-	case "title":
-		openLibraryBooks, err = s.openLibrary.SearchMultipleByTitle(query, maxResults)
-	case "author":
-		openLibraryBooks, err = s.openLibrary.SearchMultipleByAuthor(query, maxResults)
-	}
+	outcomes := s.fanOut(ctx, func(provider string) string {
+		return searchCacheKey(provider, searchType, fmt.Sprint(maxResults), query)
+	}, func(ctx context.Context, provider BookProvider) ([]*BookData, error) {
+		return searchMultiple(ctx, provider, query, searchType, maxResults)
+	})
 
-	if err == nil && len(openLibraryBooks) > 0 {
-		log.Printf("✅ Found %d books in Open Library", len(openLibraryBooks))
-	} else {
-		log.Printf("⚠️ Open Library: %v", err)
+	cacheHit := s.cache != nil
+	for _, outcome := range outcomes {
+		cacheHit = cacheHit && outcome.cached
 	}
 
-	// Merge results - prioritize Google Books, add unique results from Open Library
+	// Merge results - earlier providers first, then unique results from the rest
 	results := make([]*BookData, 0)
 	seenISBNs := make(map[string]bool)
 
-	// Add Google Books results first
-	if len(googleBooks) > 0 {
-		for _, book := range googleBooks {
+	for _, outcome := range outcomes {
+		for _, book := range outcome.books {
+			// Skip if we've already seen this ISBN
+			if (book.ISBN != "" && seenISBNs[book.ISBN]) || (book.ISBN13 != "" && seenISBNs[book.ISBN13]) {
+				continue
+			}
+
 			results = append(results, book)
 			if book.ISBN != "" {
 				seenISBNs[book.ISBN] = true
@@ -144,125 +169,259 @@ func (s *BookMergerService) SearchBooks(query, searchType string, maxResults int
 		}
 	}
 
-	// Add unique Open Library results
-	if len(openLibraryBooks) > 0 {
-		for _, book := range openLibraryBooks {
-			// Skip if we've already seen this ISBN
-			isDuplicate := false
-			if book.ISBN != "" && seenISBNs[book.ISBN] {
-				isDuplicate = true
-			}
-			if book.ISBN13 != "" && seenISBNs[book.ISBN13] {
-				isDuplicate = true
+	if len(results) == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, cacheHit, fmt.Errorf("book search aborted: %w", err)
+		}
+		return nil, cacheHit, fmt.Errorf("no books found in any source")
+	}
+
+	log.Printf("✅ Found total %d unique books", len(results))
+	return results, cacheHit, nil
+}
+
+// fanOut queries all providers in parallel under a shared deadline and
+// returns their outcomes in provider priority order, regardless of which
+// provider answered first. Providers that fail or do not answer before the
+// deadline are logged and contribute no books (partial results). Answers
+// are served from and stored in the search cache under cacheKey.
+func (s *BookMergerService) fanOut(ctx context.Context, cacheKey func(provider string) string, search func(ctx context.Context, provider BookProvider) ([]*BookData, error)) []providerResult {
+	if s.searchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.searchTimeout)
+		defer cancel()
+	}
+
+	outcomes := make([]providerResult, len(s.providers))
+	finished := make(chan int, len(s.providers))
+
+	for i, provider := range s.providers {
+		outcomes[i].provider = provider.Name()
+
+		go func(i int, provider BookProvider) {
+			providerCtx := ctx
+			if timeout := s.timeoutFor(provider.Name()); timeout > 0 {
+				var cancel context.CancelFunc
+				providerCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
 			}
-			
-			if !isDuplicate {
-				results = append(results, book)
-				if book.ISBN != "" {
-					seenISBNs[book.ISBN] = true
+
+			started := time.Now()
+			if s.cache != nil {
+				if books, ok := s.cache.Get(providerCtx, cacheKey(provider.Name())); ok {
+					outcomes[i].books = books
+					outcomes[i].cached = true
+					if len(books) == 0 {
+						outcomes[i].err = ErrNoBooksFound
+					}
+					outcomes[i].elapsed = time.Since(started)
+					finished <- i
+					return
 				}
-				if book.ISBN13 != "" {
-					seenISBNs[book.ISBN13] = true
+			}
+
+			books, err := search(providerCtx, provider)
+			for _, book := range books {
+				if book.Source == "" {
+					book.Source = provider.Name()
 				}
+				book.ISBN, book.ISBN13 = isbn.Canonical(book.ISBN, book.ISBN13)
 			}
+			s.cacheResult(providerCtx, cacheKey(provider.Name()), books, err)
+
+			// Each goroutine owns its own slot, so no locking is needed
+			outcomes[i].books = books
+			outcomes[i].err = err
+			outcomes[i].elapsed = time.Since(started)
+			finished <- i
+		}(i, provider)
+	}
+
+	// Wait for every provider or for the shared deadline, whichever is first
+	completed := make([]providerResult, len(s.providers))
+wait:
+	for remaining := len(s.providers); remaining > 0; remaining-- {
+		select {
+		case i := <-finished:
+			completed[i] = outcomes[i]
+			completed[i].done = true
+		case <-ctx.Done():
+			break wait
 		}
 	}
 
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found in any source")
+	for i := range completed {
+		name := s.providers[i].Name()
+		completed[i].provider = name
+
+		switch {
+		case !completed[i].done:
+			completed[i].err = fmt.Errorf("%s did not respond before deadline: %w", name, ctx.Err())
+			log.Printf("⚠️ %s: %v", name, completed[i].err)
+		case completed[i].err != nil || len(completed[i].books) == 0:
+			log.Printf("⚠️ %s (%v): %v", name, completed[i].elapsed.Round(time.Millisecond), completed[i].err)
+		case completed[i].cached:
+			log.Printf("🗃️ Found %d books in %s (cached)", len(completed[i].books), name)
+		default:
+			log.Printf("✅ Found %d books in %s (%v)", len(completed[i].books), name, completed[i].elapsed.Round(time.Millisecond))
+		}
 	}
 
-	log.Printf("✅ Found total %d unique books", len(results))
-	return results, nil
+	return completed
 }
 
-// mergeBookData merges book data from multiple sources
-// Priority: Google Books > Open Library
-func (s *BookMergerService) mergeBookData(googleData, openLibData *BookData, sources []string) *models.Book {
-	book := &models.Book{
-		ID:          uuid.New(),
-		QuizStatus:  "pending",
-		DataSources: pq.StringArray(sources),
+// cacheResult stores a provider answer. "Not found" answers are cached for
+// the shorter negative TTL; failures are not cached so they are retried.
+func (s *BookMergerService) cacheResult(ctx context.Context, key string, books []*BookData, err error) {
+	if s.cache == nil {
+		return
 	}
 
-	// Helper to prefer non-empty values
-	preferNonEmpty := func(primary, secondary string) string {
-		if primary != "" {
-			return primary
+	switch {
+	case err == nil && len(books) > 0:
+		s.cache.Set(ctx, key, books, s.cacheTTL)
+	case errors.Is(err, ErrNoBooksFound) || (err == nil && len(books) == 0):
+		if s.negativeCacheTTL > 0 {
+			s.cache.Set(ctx, key, []*BookData{}, s.negativeCacheTTL)
 		}
-		return secondary
 	}
+}
 
-	preferNonZero := func(primary, secondary int) int {
-		if primary > 0 {
-			return primary
-		}
-		return secondary
+// timeoutFor returns the per-provider timeout, honoring overrides
+func (s *BookMergerService) timeoutFor(provider string) time.Duration {
+	if timeout, ok := s.providerTimeouts[provider]; ok {
+		return timeout
 	}
+	return s.providerTimeout
+}
 
-	preferNonEmptySlice := func(primary, secondary []string) []string {
-		if len(primary) > 0 {
-			return primary
-		}
-		return secondary
+// normalizeQuery validates the search type and canonicalizes ISBN queries
+func normalizeQuery(query, searchType string) (string, error) {
+	if !isValidSearchType(searchType) {
+		return "", fmt.Errorf("invalid search type: %s", searchType)
+	}
+	if searchType != "isbn" {
+		return query, nil
+	}
+
+	parsed, err := isbn.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	return parsed.ISBN13, nil
+}
+
+// isValidSearchType reports whether searchType is supported by providers
+func isValidSearchType(searchType string) bool {
+	return searchType == "isbn" || searchType == "title" || searchType == "author"
+}
+
+// searchSingle dispatches a single-result search to the provider
+func searchSingle(ctx context.Context, provider BookProvider, query, searchType string) (*BookData, error) {
+	switch searchType {
+	case "isbn":
+		return provider.SearchByISBN(ctx, query)
+	case "title":
+		return provider.SearchByTitle(ctx, query)
+	case "author":
+		return provider.SearchByAuthor(ctx, query)
+	default:
+		return nil, fmt.Errorf("invalid search type: %s", searchType)
 	}
+}
+
+// searchMultiple dispatches a multi-result search to the provider
+func searchMultiple(ctx context.Context, provider BookProvider, query, searchType string, maxResults int) ([]*BookData, error) {
+	switch searchType {
+	case "isbn":
+		return provider.SearchMultipleByISBN(ctx, query, maxResults)
+	case "title":
+		return provider.SearchMultipleByTitle(ctx, query, maxResults)
+	case "author":
+		return provider.SearchMultipleByAuthor(ctx, query, maxResults)
+	default:
+		return nil, fmt.Errorf("invalid search type: %s", searchType)
+	}
+}
 
-	// Merge fields (Google Books has priority)
-	if googleData != nil {
-		book.Title = googleData.Title
-		book.Authors = pq.StringArray(googleData.Authors)
-		book.ISBN = googleData.ISBN
-		book.ISBN13 = googleData.ISBN13
-		book.Description = googleData.Description
-		book.Publisher = googleData.Publisher
-		book.PublishedDate = googleData.PublishedDate
-		book.PageCount = googleData.PageCount
-		book.Categories = pq.StringArray(googleData.Categories)
-		book.Language = googleData.Language
-		book.CoverURL = googleData.CoverURL
-		book.ThumbnailURL = googleData.ThumbnailURL
+// mergeBookData merges book data from multiple sources according to the
+// merge policy. Results must be in provider priority order. The source of
+// every populated field is recorded in the book's provenance.
+func (s *BookMergerService) mergeBookData(results []*BookData) *models.Book {
+	sources := make([]string, 0, len(results))
+	for _, data := range results {
+		sources = append(sources, data.Source)
+	}
+
+	book := &models.Book{
+		ID:          uuid.New(),
+		QuizStatus:  "pending",
+		DataSources: pq.StringArray(sources),
 	}
 
-	// Fill missing fields from Open Library
-	if openLibData != nil {
-		book.Title = preferNonEmpty(book.Title, openLibData.Title)
-		
-		if len(book.Authors) == 0 {
-			book.Authors = pq.StringArray(openLibData.Authors)
+	policy := s.policy
+	provenance := models.BookProvenance{}
+
+	record := func(field string, fieldSources ...string) {
+		if len(fieldSources) > 0 && fieldSources[0] != "" {
+			provenance[field] = fieldSources
 		}
-		
-		book.ISBN = preferNonEmpty(book.ISBN, openLibData.ISBN)
-		book.ISBN13 = preferNonEmpty(book.ISBN13, openLibData.ISBN13)
-		book.Description = preferNonEmpty(book.Description, openLibData.Description)
-		book.Publisher = preferNonEmpty(book.Publisher, openLibData.Publisher)
-		book.PublishedDate = preferNonEmpty(book.PublishedDate, openLibData.PublishedDate)
-		book.PageCount = preferNonZero(book.PageCount, openLibData.PageCount)
-		
-		currentCategories := []string(book.Categories)
-		book.Categories = pq.StringArray(preferNonEmptySlice(currentCategories, openLibData.Categories))
-		
-		book.Language = preferNonEmpty(book.Language, openLibData.Language)
-		book.CoverURL = preferNonEmpty(book.CoverURL, openLibData.CoverURL)
-		book.ThumbnailURL = preferNonEmpty(book.ThumbnailURL, openLibData.ThumbnailURL)
 	}
 
+	pickString := func(field string, target *string, get func(*BookData) string) {
+		value, source := policy.pickString(field, results, get)
+		*target = value
+		record(field, source)
+	}
+
+	pickString(models.FieldTitle, &book.Title, func(d *BookData) string { return d.Title })
+	pickString(models.FieldISBN, &book.ISBN, func(d *BookData) string { return d.ISBN })
+	pickString(models.FieldISBN13, &book.ISBN13, func(d *BookData) string { return d.ISBN13 })
+	pickString(models.FieldPublisher, &book.Publisher, func(d *BookData) string { return d.Publisher })
+	pickString(models.FieldLanguage, &book.Language, func(d *BookData) string { return d.Language })
+	pickString(models.FieldCoverURL, &book.CoverURL, func(d *BookData) string { return d.CoverURL })
+	pickString(models.FieldThumbnailURL, &book.ThumbnailURL, func(d *BookData) string { return d.ThumbnailURL })
+
+	authors, authorsSource := policy.pickSlice(models.FieldAuthors, results, func(d *BookData) []string { return d.Authors })
+	book.Authors = pq.StringArray(authors)
+	record(models.FieldAuthors, authorsSource)
+
+	pageCount, pageCountSource := policy.pickInt(models.FieldPageCount, results, func(d *BookData) int { return d.PageCount })
+	book.PageCount = pageCount
+	record(models.FieldPageCount, pageCountSource)
+
+	description, descriptionSource := policy.pickDescription(results)
+	book.Description = description
+	record(models.FieldDescription, descriptionSource)
+
+	publishedDate, publishedDateSource := policy.pickPublishedDate(results)
+	book.PublishedDate = publishedDate
+	record(models.FieldPublishedDate, publishedDateSource)
+
+	categories, categorySources := policy.pickCategories(results)
+	book.Categories = pq.StringArray(categories)
+	record(models.FieldCategories, categorySources...)
+
 	// Store raw source data for debugging
 	sourceData := map[string]interface{}{}
-	if googleData != nil {
-		sourceData["google_books"] = googleData.RawData
-	}
-	if openLibData != nil {
-		sourceData["open_library"] = openLibData.RawData
+	for _, data := range results {
+		sourceData[data.Source] = data.RawData
 	}
 
 	sourceJSON, _ := json.Marshal(sourceData)
 	book.SourceData = datatypes.JSON(sourceJSON)
 
-	// Ensure ISBN is set (required field)
-	if book.ISBN == "" {
-		book.ISBN = book.ISBN13
+	// Ensure ISBN is set (required field) and both ISBN fields are consistent
+	book.ISBN, book.ISBN13 = isbn.Canonical(book.ISBN, book.ISBN13)
+	if _, ok := provenance[models.FieldISBN]; !ok {
+		record(models.FieldISBN, provenance[models.FieldISBN13]...)
+	}
+	if _, ok := provenance[models.FieldISBN13]; !ok && book.ISBN13 != "" {
+		record(models.FieldISBN13, provenance[models.FieldISBN]...)
 	}
 
+	book.SetProvenance(provenance)
+
 	log.Printf("✅ Book merged successfully: %s (ISBN: %s) from sources: %v", book.Title, book.ISBN, sources)
 	
 	return book
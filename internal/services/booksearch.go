@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/isbn"
+	"github.com/bookwise/api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Highlight markers wrapped around matched terms
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// htmlEscapes are applied in order by escapeHTMLSQL; & comes first so the
+// other entities aren't escaped twice
+var htmlEscapes = []struct{ from, to string }{
+	{"&", "&amp;"},
+	{"<", "&lt;"},
+	{">", "&gt;"},
+}
+
+// escapeHTMLSQL wraps a SQL text expression so &, < and > are escaped
+// before ts_headline adds its markers. Titles and descriptions can be
+// edited, so the markers must be the only markup in highlights. The
+// parser reads the entities as single tokens, so matching is unaffected.
+func escapeHTMLSQL(expr string) string {
+	for _, escape := range htmlEscapes {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, escape.from, escape.to)
+	}
+	return expr
+}
+
+// LocalSearchHit is a stored book matching a full-text query
+type LocalSearchHit struct {
+	models.Book          `gorm:"embedded"`
+	Rank                 float64 // 0-1, higher is better
+	TitleHighlight       string
+	DescriptionHighlight string
+}
+
+// Highlights returns the title and description with matched terms marked
+func (h *LocalSearchHit) Highlights() map[string]string {
+	highlights := map[string]string{}
+	if h.TitleHighlight != "" {
+		highlights[models.FieldTitle] = h.TitleHighlight
+	}
+	if h.DescriptionHighlight != "" {
+		highlights[models.FieldDescription] = h.DescriptionHighlight
+	}
+	return highlights
+}
+
+// localSearchFilters restricts matches to a single field for typed searches
+var localSearchFilters = map[string]string{
+	"":       "",
+	"title":  "AND to_tsvector('simple', books.title) @@ q.query",
+	"author": "AND to_tsvector('simple', array_to_string(books.authors, ' ')) @@ q.query",
+}
+
+// SearchLocalBooks runs a full-text search over stored books matching
+// filter, best matches first. searchType is "" to match any field, or
+// "title", "author" or "isbn" (exact match on a normalized ISBN, which
+// ignores filter).
+func SearchLocalBooks(query, searchType string, filter BookFilter, limit, offset int) ([]LocalSearchHit, error) {
+	if searchType == "isbn" {
+		return searchLocalISBN(query)
+	}
+
+	search, err := localSearchQuery(database.DB, query, searchType, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []LocalSearchHit
+	if err := search.Scan(&hits).Error; err != nil {
+		return nil, fmt.Errorf("local book search failed: %w", err)
+	}
+	return hits, nil
+}
+
+// localSearchQuery builds the full-text search query. A non-empty filter
+// restricts matches to the books BookFilter.Apply selects.
+func localSearchQuery(db *gorm.DB, query, searchType string, filter BookFilter, limit, offset int) (*gorm.DB, error) {
+	typeFilter, ok := localSearchFilters[searchType]
+	if !ok {
+		return nil, fmt.Errorf("invalid search type: %s", searchType)
+	}
+
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s", HighlightStart, HighlightStop)
+	args := []interface{}{
+		query,
+		headline + ", HighlightAll=true",
+		headline + ", MaxWords=35, MinWords=15, MaxFragments=2",
+	}
+
+	bookFilter := ""
+	if filter != (BookFilter{}) {
+		bookFilter = "AND books.id IN (?)"
+		args = append(args, filter.Apply(db.Model(&models.Book{})).Select("books.id"))
+	}
+	args = append(args, limit, offset)
+
+	return db.Raw(`
+		WITH q AS (SELECT websearch_to_tsquery('simple', ?) AS query)
+		SELECT books.*,
+			ts_rank_cd(books.search_vector, q.query, 32) AS rank,
+			ts_headline('simple', `+escapeHTMLSQL("books.title")+`, q.query, ?) AS title_highlight,
+			ts_headline('simple', `+escapeHTMLSQL("coalesce(books.description, '')")+`, q.query, ?) AS description_highlight
+		FROM books, q
+		WHERE books.search_vector @@ q.query `+typeFilter+` `+bookFilter+`
+		ORDER BY rank DESC, books.created_at DESC
+		LIMIT ? OFFSET ?`,
+		args...,
+	), nil
+}
+
+// searchLocalISBN finds the stored book with the given ISBN
+func searchLocalISBN(query string) ([]LocalSearchHit, error) {
+	parsed, err := isbn.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var books []models.Book
+	variants := parsed.Variants()
+	if err := database.DB.Where("isbn IN ? OR isbn13 IN ?", variants, variants).Limit(1).Find(&books).Error; err != nil {
+		return nil, fmt.Errorf("local book search failed: %w", err)
+	}
+
+	hits := make([]LocalSearchHit, len(books))
+	for i, book := range books {
+		hits[i] = LocalSearchHit{Book: book, Rank: 1}
+	}
+	return hits, nil
+}
+
+// LocalHitsSufficient reports whether local hits are good enough to skip
+// external providers for a search of limit results
+func (s *BookMergerService) LocalHitsSufficient(hits []LocalSearchHit, limit int) bool {
+	needed := s.localMinResults
+	if limit < needed {
+		needed = limit
+	}
+	if needed <= 0 {
+		return false
+	}
+
+	good := 0
+	for _, hit := range hits {
+		if hit.Rank >= s.localMinRank {
+			good++
+		}
+	}
+	return good >= needed
+}
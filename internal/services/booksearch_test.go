@@ -0,0 +1,114 @@
+package services
+
+import (
+	"html"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestEscapeHTMLSQL(t *testing.T) {
+	want := "replace(replace(replace(books.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+	if got := escapeHTMLSQL("books.title"); got != want {
+		t.Errorf("escapeHTMLSQL() = %q, want %q", got, want)
+	}
+}
+
+// The replacements run in htmlEscapes order, innermost first; applying them
+// the same way must escape like html.EscapeString does for &, < and >
+func TestHTMLEscapesOrder(t *testing.T) {
+	tests := []string{
+		`<script>alert(1)</script>`,
+		`Tom & Jerry`,
+		`&lt;already escaped&gt;`,
+		`a < b > c & d`,
+		`Düz metin`,
+	}
+	for _, text := range tests {
+		escaped := text
+		for _, escape := range htmlEscapes {
+			escaped = strings.ReplaceAll(escaped, escape.from, escape.to)
+		}
+		want := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+		if escaped != want || html.UnescapeString(escaped) != text {
+			t.Errorf("escaping %q = %q, want %q", text, escaped, want)
+		}
+		if strings.ContainsAny(escaped, "<>") {
+			t.Errorf("escaping %q left markup: %q", text, escaped)
+		}
+	}
+}
+
+// newDryRunDB returns a Postgres session that renders SQL without a server
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open() error: %v", err)
+	}
+	return db
+}
+
+func TestLocalSearchQueryFilter(t *testing.T) {
+	db := newDryRunDB(t)
+
+	tests := []struct {
+		name       string
+		searchType string
+		filter     BookFilter
+		want       []string
+		notWant    []string
+	}{
+		{
+			name:    "no filter",
+			want:    []string{"websearch_to_tsquery('simple', 'cormen')", "LIMIT 11 OFFSET 20"},
+			notWant: []string{"books.id IN"},
+		},
+		{
+			name:   "filters apply to the search",
+			filter: BookFilter{Author: "cormen", Language: "en", PublishedFrom: 2000},
+			want: []string{
+				`books.id IN (SELECT books.id FROM "books" WHERE`,
+				"author ILIKE '%cormen%'",
+				"LOWER(books.language) = LOWER('en')",
+				">= 2000",
+				"LIMIT 11 OFFSET 20",
+			},
+		},
+		{
+			name:       "typed search with filter",
+			searchType: "title",
+			filter:     BookFilter{QuizStatus: "completed"},
+			want:       []string{"to_tsvector('simple', books.title) @@ q.query", "books.quiz_status = 'completed'"},
+		},
+	}
+	for _, tt := range tests {
+		search, err := localSearchQuery(db, "cormen", tt.searchType, tt.filter, 11, 20)
+		if err != nil {
+			t.Fatalf("%s: localSearchQuery() error: %v", tt.name, err)
+		}
+		var hits []LocalSearchHit
+		sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return search.Session(&gorm.Session{}).Scan(&hits)
+		})
+		for _, want := range tt.want {
+			if !strings.Contains(sql, want) {
+				t.Errorf("%s: query lacks %q:\n%s", tt.name, want, sql)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(sql, notWant) {
+				t.Errorf("%s: query has %q:\n%s", tt.name, notWant, sql)
+			}
+		}
+	}
+
+	if _, err := localSearchQuery(db, "cormen", "publisher", BookFilter{}, 10, 0); err == nil {
+		t.Error("localSearchQuery() with an unknown search type: want error")
+	}
+}
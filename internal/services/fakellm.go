@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+// FakeLLM is a deterministic backend for tests and local development. It
+// never calls a network service: the same prompt always yields the same quiz.
+type FakeLLM struct {
+	questionsCount int
+
+	// Err, when set, is returned by every Generate call
+	Err error
+}
+
+// NewFakeLLM creates a fake backend returning questionsCount questions
+func NewFakeLLM(questionsCount int) *FakeLLM {
+	if questionsCount < 1 {
+		questionsCount = 1
+	}
+	return &FakeLLM{questionsCount: questionsCount}
+}
+
+// Provider returns the backend name
+func (f *FakeLLM) Provider() string {
+	return QuizLLMFake
+}
+
+// Model returns the fake model name
+func (f *FakeLLM) Model() string {
+	return "deterministic"
+}
+
+// Generate returns a valid quiz derived from the prompt hash. Question
+// types cycle through the types the schema allows; a schema with a fixed
+// question count overrides questionsCount.
+func (f *FakeLLM) Generate(ctx context.Context, prompt string, schema *JSONSchema) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if f.Err != nil {
+		return "", f.Err
+	}
+
+	sum := sha256.Sum256([]byte(prompt))
+	seed := hex.EncodeToString(sum[:4])
+
+	count := f.questionsCount
+	if schema != nil {
+		if quiz := schema.Properties["quiz"]; quiz != nil && quiz.MaxItems > 0 {
+			count = quiz.MaxItems
+		}
+	}
+
+	types := schemaQuestionTypes(schema)
+	questions := make([]models.QuizQuestion, count)
+	for i := range questions {
+		questions[i] = fakeQuestion(types[i%len(types)], i, int(sum[i%len(sum)]), seed)
+	}
+
+	data, err := json.Marshal(models.QuizData{Quiz: questions})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// schemaQuestionTypes returns the question types a quiz schema allows
+func schemaQuestionTypes(schema *JSONSchema) []string {
+	if schema != nil {
+		if quiz := schema.Properties["quiz"]; quiz != nil && quiz.Items != nil {
+			if questionType := quiz.Items.Properties["type"]; questionType != nil && len(questionType.Enum) > 0 {
+				return questionType.Enum
+			}
+		}
+	}
+	return []string{models.QuestionMultipleChoice}
+}
+
+// fakeText returns a short text derived from seed and parts, distinct
+// enough from other fakeText results to pass the quality checks
+func fakeText(seed string, parts ...int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(seed, parts)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// fakeQuestion builds the i-th (0-based) question of the given type.
+// Multiple choice answers rotate through the option positions.
+func fakeQuestion(questionType string, i, n int, seed string) models.QuizQuestion {
+	question := models.QuizQuestion{
+		Type:        questionType,
+		Question:    fmt.Sprintf("Soru %d: %s", i+1, fakeText(seed, i)),
+		Explanation: fmt.Sprintf("Test açıklaması %d", i+1),
+	}
+	options := func(count int) []string {
+		options := make([]string, count)
+		for j := range options {
+			options[j] = fmt.Sprintf("%s) %s", quizOptionLetters[j], fakeText(seed, i, j))
+		}
+		return options
+	}
+
+	switch questionType {
+	case models.QuestionTrueFalse:
+		question.Answer = strconv.FormatBool(n%2 == 0)
+	case models.QuestionMultiSelect:
+		question.Options = options(multiSelectMinOptions)
+		question.Answers = []string{question.Options[n%2], question.Options[2+n%2]}
+	case models.QuestionOrdering:
+		events := []string{fakeText(seed, i, 0), fakeText(seed, i, 1), fakeText(seed, i, 2)}
+		question.Answers = events
+		question.Options = []string{events[2], events[0], events[1]}
+	case models.QuestionFillBlank:
+		question.Question = fmt.Sprintf("Soru %d: %s ___", i+1, fakeText(seed, i))
+		question.Answer = fmt.Sprintf("Cevap %d", i+1)
+	case models.QuestionShortAnswer:
+		question.Answer = fmt.Sprintf("Örnek cevap %d", i+1)
+		question.Rubric = "Anahtar kelimeyi içeren cevaplar doğrudur"
+		question.Keywords = []string{fmt.Sprintf("anahtar%d", i+1)}
+	default:
+		question.Options = options(quizOptionsCount)
+		question.Answer = question.Options[(n+i)%quizOptionsCount]
+	}
+	return question
+}
+
+// Close does nothing
+func (f *FakeLLM) Close() error {
+	return nil
+}
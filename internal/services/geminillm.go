@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// GeminiLLM generates quizzes with Google Gemini
+type GeminiLLM struct {
+	client    *genai.Client
+	modelName string
+}
+
+// NewGeminiLLM creates a new Gemini backend
+func NewGeminiLLM(apiKey, modelName string) (*GeminiLLM, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+
+	return &GeminiLLM{
+		client:    client,
+		modelName: modelName,
+	}, nil
+}
+
+// generativeModel returns a model configured for JSON output matching schema.
+// A new model is built per call because workers generate concurrently.
+func (g *GeminiLLM) generativeModel(schema *JSONSchema) *genai.GenerativeModel {
+	model := g.client.GenerativeModel(g.modelName)
+
+	model.SetTemperature(0.7)
+	model.SetTopK(40)
+	model.SetTopP(0.95)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = schema.toGenai()
+
+	return model
+}
+
+// Provider returns the backend name
+func (g *GeminiLLM) Provider() string {
+	return QuizLLMGemini
+}
+
+// Model returns the Gemini model name
+func (g *GeminiLLM) Model() string {
+	return g.modelName
+}
+
+// Generate sends the prompt to Gemini and returns the response text
+func (g *GeminiLLM) Generate(ctx context.Context, prompt string, schema *JSONSchema) (string, error) {
+	resp, err := g.generativeModel(schema).GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", fmt.Errorf("gemini api call failed: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", fmt.Errorf("no response from gemini")
+	}
+
+	var content strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			content.WriteString(string(text))
+		}
+	}
+	if content.Len() == 0 {
+		return "", fmt.Errorf("empty response from gemini")
+	}
+
+	return content.String(), nil
+}
+
+// Close closes the Gemini client
+func (g *GeminiLLM) Close() error {
+	return g.client.Close()
+}
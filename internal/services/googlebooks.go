@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/bookwise/api/internal/isbn"
 )
 
 // GoogleBooksService handles Google Books API integration
@@ -27,6 +30,11 @@ func NewGoogleBooksService(apiKey string) *GoogleBooksService {
 	}
 }
 
+// Name returns the provider identifier
+func (s *GoogleBooksService) Name() string {
+	return "google_books"
+}
+
 // GoogleBooksResponse represents the response from Google Books API
 type GoogleBooksResponse struct {
 	Kind       string `json:"kind"`
@@ -89,58 +97,58 @@ type BookData struct {
 }
 
 // SearchByISBN searches for a book by ISBN (returns single result)
-func (s *GoogleBooksService) SearchByISBN(isbn string) (*BookData, error) {
-	results, err := s.searchMultiple(fmt.Sprintf("isbn:%s", isbn), 1)
+func (s *GoogleBooksService) SearchByISBN(ctx context.Context, isbnQuery string) (*BookData, error) {
+	results, err := s.searchMultiple(ctx, fmt.Sprintf("isbn:%s", isbn.Normalize(isbnQuery)), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchByTitle searches for books by title (returns single result)
-func (s *GoogleBooksService) SearchByTitle(title string) (*BookData, error) {
-	results, err := s.searchMultiple(fmt.Sprintf("intitle:%s", title), 1)
+func (s *GoogleBooksService) SearchByTitle(ctx context.Context, title string) (*BookData, error) {
+	results, err := s.searchMultiple(ctx, fmt.Sprintf("intitle:%s", title), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchByAuthor searches for books by author (returns single result)
-func (s *GoogleBooksService) SearchByAuthor(author string) (*BookData, error) {
-	results, err := s.searchMultiple(fmt.Sprintf("inauthor:%s", author), 1)
+func (s *GoogleBooksService) SearchByAuthor(ctx context.Context, author string) (*BookData, error) {
+	results, err := s.searchMultiple(ctx, fmt.Sprintf("inauthor:%s", author), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchMultipleByISBN searches for books by ISBN (returns multiple results)
-func (s *GoogleBooksService) SearchMultipleByISBN(isbn string, maxResults int) ([]*BookData, error) {
-	return s.searchMultiple(fmt.Sprintf("isbn:%s", isbn), maxResults)
+func (s *GoogleBooksService) SearchMultipleByISBN(ctx context.Context, isbnQuery string, maxResults int) ([]*BookData, error) {
+	return s.searchMultiple(ctx, fmt.Sprintf("isbn:%s", isbn.Normalize(isbnQuery)), maxResults)
 }
 
 // SearchMultipleByTitle searches for books by title (returns multiple results)
-func (s *GoogleBooksService) SearchMultipleByTitle(title string, maxResults int) ([]*BookData, error) {
-	return s.searchMultiple(fmt.Sprintf("intitle:%s", title), maxResults)
+func (s *GoogleBooksService) SearchMultipleByTitle(ctx context.Context, title string, maxResults int) ([]*BookData, error) {
+	return s.searchMultiple(ctx, fmt.Sprintf("intitle:%s", title), maxResults)
 }
 
 // SearchMultipleByAuthor searches for books by author (returns multiple results)
-func (s *GoogleBooksService) SearchMultipleByAuthor(author string, maxResults int) ([]*BookData, error) {
-	return s.searchMultiple(fmt.Sprintf("inauthor:%s", author), maxResults)
+func (s *GoogleBooksService) SearchMultipleByAuthor(ctx context.Context, author string, maxResults int) ([]*BookData, error) {
+	return s.searchMultiple(ctx, fmt.Sprintf("inauthor:%s", author), maxResults)
 }
 
 // searchMultiple performs the actual search query and returns multiple results
-func (s *GoogleBooksService) searchMultiple(query string, maxResults int) ([]*BookData, error) {
+func (s *GoogleBooksService) searchMultiple(ctx context.Context, query string, maxResults int) ([]*BookData, error) {
 	if maxResults <= 0 {
 		maxResults = 10
 	}
@@ -157,7 +165,12 @@ func (s *GoogleBooksService) searchMultiple(query string, maxResults int) ([]*Bo
 
 	reqURL := fmt.Sprintf("%s/volumes?%s", s.BaseURL, params.Encode())
 
-	resp, err := s.HTTPClient.Get(reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build google books request: %w", err)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("google books api request failed: %w", err)
 	}
@@ -174,7 +187,7 @@ func (s *GoogleBooksService) searchMultiple(query string, maxResults int) ([]*Bo
 	}
 
 	if result.TotalItems == 0 || len(result.Items) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 
 	// Convert all results to normalized BookData
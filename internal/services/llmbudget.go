@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+)
+
+// ErrLLMBudgetExhausted is returned when the daily LLM call budget is used up
+var ErrLLMBudgetExhausted = errors.New("daily LLM call budget exhausted")
+
+// LLMBudget enforces a daily cap on LLM calls. Usage is counted in the
+// llm_usage table so the cap holds across replicas. Days are UTC.
+type LLMBudget struct {
+	daily int
+}
+
+// NewLLMBudget creates a budget of daily calls; 0 or less means unlimited
+func NewLLMBudget(daily int) *LLMBudget {
+	return &LLMBudget{daily: daily}
+}
+
+// Limited reports whether a daily cap is configured
+func (b *LLMBudget) Limited() bool {
+	return b != nil && b.daily > 0
+}
+
+// Reserve records one LLM call, or returns ErrLLMBudgetExhausted when the
+// day's budget is used up. The check and increment are a single statement,
+// so concurrent workers can't overshoot the cap.
+func (b *LLMBudget) Reserve() error {
+	if !b.Limited() {
+		return nil
+	}
+
+	var calls []int
+	err := database.DB.Raw(`
+		INSERT INTO llm_usage (day, calls, updated_at) VALUES (?, 1, NOW())
+		ON CONFLICT (day) DO UPDATE SET calls = llm_usage.calls + 1, updated_at = NOW()
+		WHERE llm_usage.calls < ?
+		RETURNING calls`, budgetDay(time.Now()), b.daily).
+		Scan(&calls).Error
+	if err != nil {
+		return fmt.Errorf("failed to reserve LLM budget: %w", err)
+	}
+	if len(calls) == 0 {
+		return ErrLLMBudgetExhausted
+	}
+	return nil
+}
+
+// Used returns the number of LLM calls made today
+func (b *LLMBudget) Used() (int, error) {
+	var usage models.LLMUsage
+	err := database.DB.Where("day = ?", budgetDay(time.Now())).Limit(1).Find(&usage).Error
+	return usage.Calls, err
+}
+
+// Exhausted reports whether today's budget is used up
+func (b *LLMBudget) Exhausted() bool {
+	if !b.Limited() {
+		return false
+	}
+	used, err := b.Used()
+	if err != nil {
+		log.Printf("⚠️ Failed to read LLM budget usage: %v", err)
+		return false
+	}
+	return used >= b.daily
+}
+
+// Stats returns budget usage for health and admin endpoints
+func (b *LLMBudget) Stats() map[string]interface{} {
+	if !b.Limited() {
+		return map[string]interface{}{"daily_limit": 0}
+	}
+	used, _ := b.Used()
+	return map[string]interface{}{
+		"daily_limit": b.daily,
+		"used_today":  used,
+		"resets_at":   NextBudgetReset(time.Now()),
+	}
+}
+
+// NextBudgetReset returns the start of the next UTC day
+func NextBudgetReset(now time.Time) time.Time {
+	return budgetDay(now).AddDate(0, 0, 1)
+}
+
+// budgetDay returns the start of the UTC day containing now
+func budgetDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
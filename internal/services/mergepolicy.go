@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/models"
+)
+
+// Merge strategies for fields that support more than plain priority
+const (
+	MergeStrategyPriority = "priority" // first non-empty value in priority order
+	MergeStrategyLongest  = "longest"  // description: longest value wins
+	MergeStrategyUnion    = "union"    // categories: union of all sources
+	MergeStrategyEarliest = "earliest" // published_date: earliest parsable date wins
+)
+
+// MergePolicy controls how BookData from multiple providers is combined
+type MergePolicy struct {
+	// FieldPriority overrides the provider order for individual fields.
+	// Providers not listed keep their default order after the listed ones.
+	FieldPriority map[string][]string
+
+	DescriptionStrategy   string
+	CategoriesStrategy    string
+	PublishedDateStrategy string
+}
+
+// NewMergePolicy builds a merge policy from configuration. Unknown
+// strategies are rejected so a typo doesn't silently fall back to priority.
+func NewMergePolicy(cfg config.MergeConfig) (*MergePolicy, error) {
+	strategies := []struct {
+		env   string
+		value string
+		other string // The strategy the field supports besides priority
+	}{
+		{"MERGE_DESCRIPTION_STRATEGY", cfg.DescriptionStrategy, MergeStrategyLongest},
+		{"MERGE_CATEGORIES_STRATEGY", cfg.CategoriesStrategy, MergeStrategyUnion},
+		{"MERGE_PUBLISHED_DATE_STRATEGY", cfg.PublishedDateStrategy, MergeStrategyEarliest},
+	}
+	for _, strategy := range strategies {
+		if strategy.value != MergeStrategyPriority && strategy.value != strategy.other {
+			return nil, fmt.Errorf("unknown %s %q (expected %s or %s)", strategy.env, strategy.value, MergeStrategyPriority, strategy.other)
+		}
+	}
+
+	return &MergePolicy{
+		FieldPriority:         cfg.FieldPriority,
+		DescriptionStrategy:   cfg.DescriptionStrategy,
+		CategoriesStrategy:    cfg.CategoriesStrategy,
+		PublishedDateStrategy: cfg.PublishedDateStrategy,
+	}, nil
+}
+
+// order returns results reordered by the field's priority list
+func (p *MergePolicy) order(field string, results []*BookData) []*BookData {
+	priority := p.FieldPriority[field]
+	if len(priority) == 0 {
+		return results
+	}
+
+	ordered := make([]*BookData, 0, len(results))
+	used := make([]bool, len(results))
+
+	for _, source := range priority {
+		for i, data := range results {
+			if !used[i] && data.Source == source {
+				ordered = append(ordered, data)
+				used[i] = true
+			}
+		}
+	}
+	for i, data := range results {
+		if !used[i] {
+			ordered = append(ordered, data)
+		}
+	}
+
+	return ordered
+}
+
+// pickString returns the first non-empty value in field priority order
+func (p *MergePolicy) pickString(field string, results []*BookData, get func(*BookData) string) (string, string) {
+	for _, data := range p.order(field, results) {
+		if value := strings.TrimSpace(get(data)); value != "" {
+			return value, data.Source
+		}
+	}
+	return "", ""
+}
+
+// pickInt returns the first positive value in field priority order
+func (p *MergePolicy) pickInt(field string, results []*BookData, get func(*BookData) int) (int, string) {
+	for _, data := range p.order(field, results) {
+		if value := get(data); value > 0 {
+			return value, data.Source
+		}
+	}
+	return 0, ""
+}
+
+// pickSlice returns the first non-empty slice in field priority order
+func (p *MergePolicy) pickSlice(field string, results []*BookData, get func(*BookData) []string) ([]string, string) {
+	for _, data := range p.order(field, results) {
+		if value := get(data); len(value) > 0 {
+			return value, data.Source
+		}
+	}
+	return nil, ""
+}
+
+// pickDescription applies the description strategy
+func (p *MergePolicy) pickDescription(results []*BookData) (string, string) {
+	get := func(d *BookData) string { return d.Description }
+	if p.DescriptionStrategy != MergeStrategyLongest {
+		return p.pickString(models.FieldDescription, results, get)
+	}
+
+	// Ties are resolved by field priority order
+	var best, source string
+	for _, data := range p.order(models.FieldDescription, results) {
+		value := strings.TrimSpace(data.Description)
+		if len([]rune(value)) > len([]rune(best)) {
+			best, source = value, data.Source
+		}
+	}
+	return best, source
+}
+
+// pickCategories applies the categories strategy and returns all contributing sources
+func (p *MergePolicy) pickCategories(results []*BookData) ([]string, []string) {
+	get := func(d *BookData) []string { return d.Categories }
+	if p.CategoriesStrategy != MergeStrategyUnion {
+		value, source := p.pickSlice(models.FieldCategories, results, get)
+		if source == "" {
+			return nil, nil
+		}
+		return value, []string{source}
+	}
+
+	categories := []string{}
+	sources := []string{}
+	seen := make(map[string]bool)
+
+	for _, data := range p.order(models.FieldCategories, results) {
+		contributed := false
+		for _, category := range data.Categories {
+			category = strings.TrimSpace(category)
+			key := strings.ToLower(category)
+			if category == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			categories = append(categories, category)
+			contributed = true
+		}
+		if contributed {
+			sources = append(sources, data.Source)
+		}
+	}
+
+	return categories, sources
+}
+
+// pickPublishedDate applies the published date strategy
+func (p *MergePolicy) pickPublishedDate(results []*BookData) (string, string) {
+	get := func(d *BookData) string { return d.PublishedDate }
+	if p.PublishedDateStrategy != MergeStrategyEarliest {
+		return p.pickString(models.FieldPublishedDate, results, get)
+	}
+
+	var best *publishedDate
+	var value, source string
+
+	for _, data := range p.order(models.FieldPublishedDate, results) {
+		parsed, ok := parsePublishedDate(data.PublishedDate)
+		if !ok {
+			continue
+		}
+		if best == nil || parsed.before(best) {
+			best = parsed
+			value, source = strings.TrimSpace(data.PublishedDate), data.Source
+		}
+	}
+
+	// Nothing parsable, fall back to priority
+	if best == nil {
+		return p.pickString(models.FieldPublishedDate, results, get)
+	}
+	return value, source
+}
+
+// publishedDate is a parsed publication date with its precision
+type publishedDate struct {
+	time      time.Time
+	precision int // 1 = year, 2 = month, 3 = day
+}
+
+// before reports whether d is earlier than other. Within the same year a
+// more precise date is preferred, so "2009-07-31" beats a bare "2009".
+func (d *publishedDate) before(other *publishedDate) bool {
+	if d.time.Year() != other.time.Year() {
+		return d.time.Year() < other.time.Year()
+	}
+	if d.precision != other.precision {
+		return d.precision > other.precision
+	}
+	return d.time.Before(other.time)
+}
+
+var (
+	publishedDateLayouts = []struct {
+		layout    string
+		precision int
+	}{
+		{"2006-01-02", 3},
+		{"January 2, 2006", 3},
+		{"Jan 2, 2006", 3},
+		{"2 January 2006", 3},
+		{"2006-01", 2},
+		{"January 2006", 2},
+		{"Jan 2006", 2},
+		{"2006", 1},
+	}
+
+	yearPattern = regexp.MustCompile(`(?:^|[^0-9])(1[0-9]{3}|20[0-9]{2})(?:[^0-9]|$)`)
+)
+
+// parsePublishedDate parses the date formats returned by providers
+func parsePublishedDate(value string) (*publishedDate, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, false
+	}
+
+	for _, candidate := range publishedDateLayouts {
+		if t, err := time.Parse(candidate.layout, value); err == nil {
+			return &publishedDate{time: t, precision: candidate.precision}, true
+		}
+	}
+
+	// Free-form values such as "c1998" or "1998?" still carry a year
+	if match := yearPattern.FindStringSubmatch(value); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		return &publishedDate{time: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), precision: 1}, true
+	}
+
+	return nil, false
+}
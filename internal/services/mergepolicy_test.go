@@ -0,0 +1,183 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/models"
+)
+
+func TestNewMergePolicy(t *testing.T) {
+	valid := config.MergeConfig{
+		DescriptionStrategy:   MergeStrategyLongest,
+		CategoriesStrategy:    MergeStrategyUnion,
+		PublishedDateStrategy: MergeStrategyEarliest,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.MergeConfig)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(cfg *config.MergeConfig) {}},
+		{name: "all priority", modify: func(cfg *config.MergeConfig) {
+			cfg.DescriptionStrategy = MergeStrategyPriority
+			cfg.CategoriesStrategy = MergeStrategyPriority
+			cfg.PublishedDateStrategy = MergeStrategyPriority
+		}},
+		{name: "typo", modify: func(cfg *config.MergeConfig) { cfg.DescriptionStrategy = "longst" }, wantErr: true},
+		{name: "strategy of another field", modify: func(cfg *config.MergeConfig) { cfg.CategoriesStrategy = MergeStrategyLongest }, wantErr: true},
+		{name: "empty", modify: func(cfg *config.MergeConfig) { cfg.PublishedDateStrategy = "" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg := valid
+		tt.modify(&cfg)
+		policy, err := NewMergePolicy(cfg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: NewMergePolicy() = %+v, want error", tt.name, policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewMergePolicy() unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func newTestMergePolicy(t *testing.T, description, categories, publishedDate string, priority map[string][]string) *MergePolicy {
+	t.Helper()
+	policy, err := NewMergePolicy(config.MergeConfig{
+		FieldPriority:         priority,
+		DescriptionStrategy:   description,
+		CategoriesStrategy:    categories,
+		PublishedDateStrategy: publishedDate,
+	})
+	if err != nil {
+		t.Fatalf("NewMergePolicy() error: %v", err)
+	}
+	return policy
+}
+
+func TestMergePolicyPickDescription(t *testing.T) {
+	results := []*BookData{
+		{Source: "google_books", Description: "Short"},
+		{Source: "openlibrary", Description: "  A much longer description  "},
+		{Source: "isbndb", Description: ""},
+	}
+
+	tests := []struct {
+		name       string
+		strategy   string
+		priority   map[string][]string
+		results    []*BookData
+		wantValue  string
+		wantSource string
+	}{
+		{"longest", MergeStrategyLongest, nil, results, "A much longer description", "openlibrary"},
+		{"priority keeps provider order", MergeStrategyPriority, nil, results, "Short", "google_books"},
+		{"priority override", MergeStrategyPriority, map[string][]string{models.FieldDescription: {"openlibrary"}}, results, "A much longer description", "openlibrary"},
+		{"longest tie goes to priority", MergeStrategyLongest, map[string][]string{models.FieldDescription: {"b"}},
+			[]*BookData{{Source: "a", Description: "same"}, {Source: "b", Description: "SAME"}}, "SAME", "b"},
+		{"nothing set", MergeStrategyLongest, nil, []*BookData{{Source: "a"}}, "", ""},
+	}
+	for _, tt := range tests {
+		policy := newTestMergePolicy(t, tt.strategy, MergeStrategyUnion, MergeStrategyEarliest, tt.priority)
+		value, source := policy.pickDescription(tt.results)
+		if value != tt.wantValue || source != tt.wantSource {
+			t.Errorf("%s: pickDescription() = %q, %q, want %q, %q", tt.name, value, source, tt.wantValue, tt.wantSource)
+		}
+	}
+}
+
+func TestMergePolicyPickCategories(t *testing.T) {
+	results := []*BookData{
+		{Source: "google_books", Categories: []string{"Computers", " Algorithms "}},
+		{Source: "openlibrary", Categories: []string{"algorithms", "Data structures", ""}},
+		{Source: "isbndb", Categories: []string{"computers"}},
+	}
+
+	tests := []struct {
+		name        string
+		strategy    string
+		results     []*BookData
+		wantValue   []string
+		wantSources []string
+	}{
+		{"union", MergeStrategyUnion, results, []string{"Computers", "Algorithms", "Data structures"}, []string{"google_books", "openlibrary"}},
+		{"priority", MergeStrategyPriority, results, []string{"Computers", " Algorithms "}, []string{"google_books"}},
+		{"priority without categories", MergeStrategyPriority, []*BookData{{Source: "a"}}, nil, nil},
+		{"union without categories", MergeStrategyUnion, []*BookData{{Source: "a"}}, []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		policy := newTestMergePolicy(t, MergeStrategyLongest, tt.strategy, MergeStrategyEarliest, nil)
+		value, sources := policy.pickCategories(tt.results)
+		if !slices.Equal(value, tt.wantValue) || !slices.Equal(sources, tt.wantSources) {
+			t.Errorf("%s: pickCategories() = %q, %q, want %q, %q", tt.name, value, sources, tt.wantValue, tt.wantSources)
+		}
+	}
+}
+
+func TestMergePolicyPickPublishedDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		results    []*BookData
+		wantValue  string
+		wantSource string
+	}{
+		{"earliest year", MergeStrategyEarliest,
+			[]*BookData{{Source: "a", PublishedDate: "2009-07-31"}, {Source: "b", PublishedDate: "1990"}}, "1990", "b"},
+		{"precise date wins within a year", MergeStrategyEarliest,
+			[]*BookData{{Source: "a", PublishedDate: "2009"}, {Source: "b", PublishedDate: "2009-07-31"}}, "2009-07-31", "b"},
+		{"earlier day", MergeStrategyEarliest,
+			[]*BookData{{Source: "a", PublishedDate: "July 31, 2009"}, {Source: "b", PublishedDate: "2009-03-01"}}, "2009-03-01", "b"},
+		{"free-form year", MergeStrategyEarliest,
+			[]*BookData{{Source: "a", PublishedDate: "2001"}, {Source: "b", PublishedDate: "c1998"}}, "c1998", "b"},
+		{"unparsable falls back to priority", MergeStrategyEarliest,
+			[]*BookData{{Source: "a", PublishedDate: "unknown"}, {Source: "b", PublishedDate: "n.d."}}, "unknown", "a"},
+		{"priority", MergeStrategyPriority,
+			[]*BookData{{Source: "a", PublishedDate: "2009"}, {Source: "b", PublishedDate: "1990"}}, "2009", "a"},
+	}
+	for _, tt := range tests {
+		policy := newTestMergePolicy(t, MergeStrategyLongest, MergeStrategyUnion, tt.strategy, nil)
+		value, source := policy.pickPublishedDate(tt.results)
+		if value != tt.wantValue || source != tt.wantSource {
+			t.Errorf("%s: pickPublishedDate() = %q, %q, want %q, %q", tt.name, value, source, tt.wantValue, tt.wantSource)
+		}
+	}
+}
+
+func TestParsePublishedDate(t *testing.T) {
+	tests := []struct {
+		value         string
+		wantYear      int
+		wantPrecision int
+		wantOK        bool
+	}{
+		{"2009-07-31", 2009, 3, true},
+		{"July 31, 2009", 2009, 3, true},
+		{"Jul 31, 2009", 2009, 3, true},
+		{"31 July 2009", 2009, 3, true},
+		{"2009-07", 2009, 2, true},
+		{"July 2009", 2009, 2, true},
+		{"2009", 2009, 1, true},
+		{"c1998", 1998, 1, true},
+		{"1998?", 1998, 1, true},
+		{"", 0, 0, false},
+		{"unknown", 0, 0, false},
+		{"12345", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePublishedDate(tt.value)
+		if ok != tt.wantOK {
+			t.Errorf("parsePublishedDate(%q) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			continue
+		}
+		if ok && (got.time.Year() != tt.wantYear || got.precision != tt.wantPrecision) {
+			t.Errorf("parsePublishedDate(%q) = %d (precision %d), want %d (precision %d)",
+				tt.value, got.time.Year(), got.precision, tt.wantYear, tt.wantPrecision)
+		}
+	}
+}
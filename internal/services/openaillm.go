@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAILLM generates quizzes through an OpenAI-compatible chat completions
+// API. Besides OpenAI itself this works against local servers such as
+// llama.cpp or Ollama (e.g. OPENAI_BASE_URL=http://localhost:11434/v1).
+type OpenAILLM struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	modelName  string
+}
+
+// NewOpenAILLM creates a new OpenAI-compatible backend. The API key is
+// optional because local servers usually don't require one.
+func NewOpenAILLM(baseURL, apiKey, modelName string) (*OpenAILLM, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("OPENAI_BASE_URL is not set")
+	}
+	if modelName == "" {
+		return nil, fmt.Errorf("OPENAI_MODEL is not set")
+	}
+
+	return &OpenAILLM{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+		modelName: modelName,
+	}, nil
+}
+
+// openAIChatRequest represents a chat completions request
+type openAIChatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []openAIChatMessage `json:"messages"`
+	Temperature    float64             `json:"temperature"`
+	TopP           float64             `json:"top_p"`
+	ResponseFormat *openAIFormat       `json:"response_format,omitempty"`
+}
+
+// openAIChatMessage represents a single chat message
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIFormat requests JSON output, constrained by a schema when given
+type openAIFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openAIJSONSchema is the named schema of a "json_schema" response format
+type openAIJSONSchema struct {
+	Name   string      `json:"name"`
+	Schema *JSONSchema `json:"schema"`
+}
+
+// openAIChatResponse represents a chat completions response
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIChatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Provider returns the backend name
+func (o *OpenAILLM) Provider() string {
+	return QuizLLMOpenAI
+}
+
+// Model returns the model name sent to the API
+func (o *OpenAILLM) Model() string {
+	return o.modelName
+}
+
+// Generate sends the prompt as a single user message and returns the reply
+func (o *OpenAILLM) Generate(ctx context.Context, prompt string, schema *JSONSchema) (string, error) {
+	format := &openAIFormat{Type: "json_object"}
+	if schema != nil {
+		format = &openAIFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "quiz", Schema: schema},
+		}
+	}
+
+	body, err := json.Marshal(openAIChatRequest{
+		Model: o.modelName,
+		Messages: []openAIChatMessage{
+			{Role: "user", Content: prompt},
+		},
+		Temperature:    0.7,
+		TopP:           0.95,
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode openai request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build openai request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai api call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("openai api returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode openai response: %w", err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("openai api error: %s", result.Error.Message)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no response from openai")
+	}
+
+	content := strings.TrimSpace(result.Choices[0].Message.Content)
+	if content == "" {
+		return "", fmt.Errorf("empty response from openai")
+	}
+	return content, nil
+}
+
+// Close releases idle HTTP connections
+func (o *OpenAILLM) Close() error {
+	o.HTTPClient.CloseIdleConnections()
+	return nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,6 +9,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/bookwise/api/internal/isbn"
 )
 
 // OpenLibraryService handles Open Library API integration
@@ -26,6 +29,11 @@ func NewOpenLibraryService() *OpenLibraryService {
 	}
 }
 
+// Name returns the provider identifier
+func (s *OpenLibraryService) Name() string {
+	return "open_library"
+}
+
 // OpenLibraryBookResponse represents the response from Open Library
 type OpenLibraryBookResponse struct {
 	Key               string                    `json:"key"`
@@ -73,14 +81,19 @@ type OpenLibrarySearchDoc struct {
 }
 
 // SearchByISBN searches for a book by ISBN (returns single result)
-func (s *OpenLibraryService) SearchByISBN(isbn string) (*BookData, error) {
-	// Clean ISBN (remove hyphens)
-	cleanISBN := strings.ReplaceAll(isbn, "-", "")
+func (s *OpenLibraryService) SearchByISBN(ctx context.Context, isbnQuery string) (*BookData, error) {
+	// Clean ISBN (remove hyphens and spaces)
+	cleanISBN := isbn.Normalize(isbnQuery)
 	
 	// Try ISBN API first
 	reqURL := fmt.Sprintf("%s/isbn/%s.json", s.BaseURL, cleanISBN)
 	
-	resp, err := s.HTTPClient.Get(reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build openlibrary request: %w", err)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("openlibrary api request failed: %w", err)
 	}
@@ -95,58 +108,58 @@ func (s *OpenLibraryService) SearchByISBN(isbn string) (*BookData, error) {
 	}
 
 	// Fallback to search API
-	results, err := s.searchMultipleByQuery(fmt.Sprintf("isbn:%s", cleanISBN), 1)
+	results, err := s.searchMultipleByQuery(ctx, fmt.Sprintf("isbn:%s", cleanISBN), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchByTitle searches for a book by title (returns single result)
-func (s *OpenLibraryService) SearchByTitle(title string) (*BookData, error) {
-	results, err := s.searchMultipleByQuery(fmt.Sprintf("title:%s", title), 1)
+func (s *OpenLibraryService) SearchByTitle(ctx context.Context, title string) (*BookData, error) {
+	results, err := s.searchMultipleByQuery(ctx, fmt.Sprintf("title:%s", title), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchByAuthor searches for books by author (returns single result)
-func (s *OpenLibraryService) SearchByAuthor(author string) (*BookData, error) {
-	results, err := s.searchMultipleByQuery(fmt.Sprintf("author:%s", author), 1)
+func (s *OpenLibraryService) SearchByAuthor(ctx context.Context, author string) (*BookData, error) {
+	results, err := s.searchMultipleByQuery(ctx, fmt.Sprintf("author:%s", author), 1)
 	if err != nil {
 		return nil, err
 	}
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 	return results[0], nil
 }
 
 // SearchMultipleByISBN searches for books by ISBN (returns multiple results)
-func (s *OpenLibraryService) SearchMultipleByISBN(isbn string, maxResults int) ([]*BookData, error) {
-	cleanISBN := strings.ReplaceAll(isbn, "-", "")
-	return s.searchMultipleByQuery(fmt.Sprintf("isbn:%s", cleanISBN), maxResults)
+func (s *OpenLibraryService) SearchMultipleByISBN(ctx context.Context, isbnQuery string, maxResults int) ([]*BookData, error) {
+	cleanISBN := isbn.Normalize(isbnQuery)
+	return s.searchMultipleByQuery(ctx, fmt.Sprintf("isbn:%s", cleanISBN), maxResults)
 }
 
 // SearchMultipleByTitle searches for books by title (returns multiple results)
-func (s *OpenLibraryService) SearchMultipleByTitle(title string, maxResults int) ([]*BookData, error) {
-	return s.searchMultipleByQuery(fmt.Sprintf("title:%s", title), maxResults)
+func (s *OpenLibraryService) SearchMultipleByTitle(ctx context.Context, title string, maxResults int) ([]*BookData, error) {
+	return s.searchMultipleByQuery(ctx, fmt.Sprintf("title:%s", title), maxResults)
 }
 
 // SearchMultipleByAuthor searches for books by author (returns multiple results)
-func (s *OpenLibraryService) SearchMultipleByAuthor(author string, maxResults int) ([]*BookData, error) {
-	return s.searchMultipleByQuery(fmt.Sprintf("author:%s", author), maxResults)
+func (s *OpenLibraryService) SearchMultipleByAuthor(ctx context.Context, author string, maxResults int) ([]*BookData, error) {
+	return s.searchMultipleByQuery(ctx, fmt.Sprintf("author:%s", author), maxResults)
 }
 
 // searchMultipleByQuery performs a search query and returns multiple results
-func (s *OpenLibraryService) searchMultipleByQuery(query string, maxResults int) ([]*BookData, error) {
+func (s *OpenLibraryService) searchMultipleByQuery(ctx context.Context, query string, maxResults int) ([]*BookData, error) {
 	if maxResults <= 0 {
 		maxResults = 10
 	}
@@ -160,7 +173,12 @@ func (s *OpenLibraryService) searchMultipleByQuery(query string, maxResults int)
 
 	reqURL := fmt.Sprintf("%s/search.json?%s", s.BaseURL, params.Encode())
 
-	resp, err := s.HTTPClient.Get(reqURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build openlibrary search request: %w", err)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("openlibrary search failed: %w", err)
 	}
@@ -177,7 +195,7 @@ func (s *OpenLibraryService) searchMultipleByQuery(query string, maxResults int)
 	}
 
 	if result.NumFound == 0 || len(result.Docs) == 0 {
-		return nil, fmt.Errorf("no books found")
+		return nil, ErrNoBooksFound
 	}
 
 	// Convert all results to BookData
@@ -253,13 +271,13 @@ func (s *OpenLibraryService) searchDocToBookData(doc *OpenLibrarySearchDoc) *Boo
 
 	// Extract ISBN
 	if len(doc.ISBN) > 0 {
-		for _, isbn := range doc.ISBN {
-			if len(isbn) == 13 {
-				bookData.ISBN13 = isbn
-				bookData.ISBN = isbn
+		for _, value := range doc.ISBN {
+			if len(value) == 13 {
+				bookData.ISBN13 = value
+				bookData.ISBN = value
 				break
-			} else if len(isbn) == 10 && bookData.ISBN == "" {
-				bookData.ISBN = isbn
+			} else if len(value) == 10 && bookData.ISBN == "" {
+				bookData.ISBN = value
 			}
 		}
 	}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bookwise/api/config"
+)
+
+// ErrNoBooksFound is returned by providers when a search has no results.
+// Unlike other errors it is a definite answer and may be cached.
+var ErrNoBooksFound = errors.New("no books found")
+
+// BookProvider is an external (or in-house) source of book metadata.
+// Implementations return normalized BookData with Source set to Name(),
+// return ErrNoBooksFound when nothing matches and must abort outstanding
+// requests when ctx is done.
+type BookProvider interface {
+	// Name returns the unique provider identifier (e.g. "google_books")
+	Name() string
+
+	SearchByISBN(ctx context.Context, isbn string) (*BookData, error)
+	SearchByTitle(ctx context.Context, title string) (*BookData, error)
+	SearchByAuthor(ctx context.Context, author string) (*BookData, error)
+
+	SearchMultipleByISBN(ctx context.Context, isbn string, maxResults int) ([]*BookData, error)
+	SearchMultipleByTitle(ctx context.Context, title string, maxResults int) ([]*BookData, error)
+	SearchMultipleByAuthor(ctx context.Context, author string, maxResults int) ([]*BookData, error)
+}
+
+// Compile-time checks that built-in providers implement BookProvider
+var (
+	_ BookProvider = (*GoogleBooksService)(nil)
+	_ BookProvider = (*OpenLibraryService)(nil)
+)
+
+// ProviderFactory builds a provider from application configuration
+type ProviderFactory func(cfg *config.Config) (BookProvider, error)
+
+var (
+	providerRegistryMu sync.RWMutex
+	providerRegistry   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider makes a provider available under the given name.
+// It is intended to be called from init functions.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providerRegistryMu.Lock()
+	defer providerRegistryMu.Unlock()
+
+	if factory == nil {
+		panic("services: RegisterProvider factory is nil")
+	}
+	if _, exists := providerRegistry[name]; exists {
+		panic("services: RegisterProvider called twice for provider " + name)
+	}
+	providerRegistry[name] = factory
+}
+
+// RegisteredProviders returns the names of all registered providers
+func RegisteredProviders() []string {
+	providerRegistryMu.RLock()
+	defer providerRegistryMu.RUnlock()
+
+	names := make([]string, 0, len(providerRegistry))
+	for name := range providerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvidersFromConfig builds the ordered provider list configured in
+// cfg.APIs.BookProviders. Order defines merge priority.
+func NewProvidersFromConfig(cfg *config.Config) ([]BookProvider, error) {
+	providerRegistryMu.RLock()
+	defer providerRegistryMu.RUnlock()
+
+	if len(cfg.APIs.BookProviders) == 0 {
+		return nil, fmt.Errorf("no book providers configured")
+	}
+
+	providers := make([]BookProvider, 0, len(cfg.APIs.BookProviders))
+	seen := make(map[string]bool)
+
+	for _, name := range cfg.APIs.BookProviders {
+		if seen[name] {
+			return nil, fmt.Errorf("book provider %q configured more than once", name)
+		}
+		seen[name] = true
+
+		factory, ok := providerRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown book provider %q", name)
+		}
+
+		provider, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create book provider %q: %w", name, err)
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func init() {
+	RegisterProvider("google_books", func(cfg *config.Config) (BookProvider, error) {
+		return NewGoogleBooksService(cfg.APIs.GoogleBooksAPIKey), nil
+	})
+	RegisterProvider("open_library", func(cfg *config.Config) (BookProvider, error) {
+		return NewOpenLibraryService(), nil
+	})
+}
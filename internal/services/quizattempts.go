@@ -0,0 +1,274 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Quiz attempt errors
+var (
+	ErrQuizNotPlayable = errors.New("quiz is not completed")
+	ErrInvalidAnswers  = errors.New("invalid answers")
+)
+
+// SubmitQuizAttempt scores answers against the given quiz version and
+// stores the attempt for userID (nil for anonymous players). answers holds
+// one entry per question in order, empty to skip the question; see
+// ScoreQuiz for the accepted forms.
+func SubmitQuizAttempt(quizID uuid.UUID, userID *uuid.UUID, answers []models.SubmittedAnswer) (*models.QuizAttempt, []models.QuizAttemptAnswer, error) {
+	var quiz models.Quiz
+	err := database.DB.Where("id = ?", quizID).First(&quiz).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrQuizNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if quiz.Status != "completed" {
+		return nil, nil, ErrQuizNotPlayable
+	}
+
+	questions, err := quiz.ParseQuestions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse quiz questions: %w", err)
+	}
+	if len(answers) != len(questions) {
+		return nil, nil, fmt.Errorf("%w: expected %d answers, got %d", ErrInvalidAnswers, len(questions), len(answers))
+	}
+
+	results := ScoreQuiz(questions, answers)
+	score := 0
+	points := 0.0
+	for _, result := range results {
+		if result.Correct {
+			score++
+		}
+		points += result.Score
+	}
+
+	answersJSON, err := json.Marshal(results)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal answers: %w", err)
+	}
+
+	attempt := &models.QuizAttempt{
+		QuizID:  quiz.ID,
+		BookID:  quiz.BookID,
+		UserID:  userID,
+		Score:   score,
+		Points:  math.Round(points*100) / 100,
+		Total:   len(questions),
+		Answers: answersJSON,
+	}
+	if err := database.DB.Create(attempt).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save quiz attempt: %w", err)
+	}
+
+	log.Printf("📝 Quiz attempt %s scored %d/%d, %.2f points (quiz: %s)", attempt.ID, score, attempt.Total, attempt.Points, quiz.ID)
+	return attempt, results, nil
+}
+
+// Short answer grading
+const (
+	// shortAnswerKeywordShare is the share of a short answer question's
+	// keywords an answer must mention for full credit
+	shortAnswerKeywordShare = 0.5
+	// Answers longer than shortAnswerLengthFactor times the model answer
+	// (and at least shortAnswerMinWords) have their credit scaled down, so
+	// listing every likely keyword doesn't earn full credit
+	shortAnswerLengthFactor = 3
+	shortAnswerMinWords     = 30
+)
+
+// ScoreQuiz grades answers against questions, which must have equal
+// length. Accepted answers per question type:
+//   - multiple_choice: the full option ("B) ..."), its letter ("B") or its text
+//   - true_false: "true"/"false" (or "doğru"/"yanlış")
+//   - multi_select: the picked options or letters, as a list or "A,C"
+//   - ordering: every option, in order
+//   - fill_blank, short_answer: free text
+//
+// multi_select, ordering and short_answer questions earn partial credit.
+func ScoreQuiz(questions []models.QuizQuestion, answers []models.SubmittedAnswer) []models.QuizAttemptAnswer {
+	results := make([]models.QuizAttemptAnswer, len(questions))
+	for i, q := range questions {
+		result := models.QuizAttemptAnswer{
+			Question:      i + 1,
+			Type:          q.QuestionType(),
+			CorrectAnswer: q.Answer,
+			Explanation:   q.Explanation,
+		}
+
+		switch result.Type {
+		case models.QuestionTrueFalse:
+			result.Answer = answers[i].Text()
+			if value, ok := parseTrueFalse(result.Answer); ok {
+				result.Answer = strconv.FormatBool(value)
+				result.Score = boolScore(result.Answer == q.Answer)
+			}
+
+		case models.QuestionMultiSelect:
+			result.Answers = matchOptions(q.Options, answers[i].Items())
+			result.CorrectAnswers = q.Answers
+			result.Score = multiSelectScore(q.Answers, result.Answers)
+
+		case models.QuestionOrdering:
+			result.Answers = matchOptions(q.Options, answers[i].Items())
+			result.CorrectAnswers = q.Answers
+			result.Score = orderingScore(q.Answers, result.Answers)
+
+		case models.QuestionFillBlank:
+			result.Answer = answers[i].Text()
+			result.CorrectAnswers = q.Answers
+			if result.Answer != "" {
+				normalized := normalizeAnswerText(result.Answer)
+				for _, accepted := range append([]string{q.Answer}, q.Answers...) {
+					if normalized == normalizeAnswerText(accepted) {
+						result.Score = 1
+						break
+					}
+				}
+			}
+
+		case models.QuestionShortAnswer:
+			result.Answer = answers[i].Text()
+			result.Score = shortAnswerScore(q, result.Answer)
+
+		default:
+			answer := answers[i].Text()
+			if answer != "" && optionIndex(q.Options, answer) < 0 {
+				if j := matchAnswer(q.Options, answer); j >= 0 {
+					answer = q.Options[j]
+				}
+			}
+			result.Answer = answer
+			result.Score = boolScore(answer != "" && answer == q.Answer)
+		}
+
+		result.Score = math.Round(result.Score*100) / 100
+		result.Correct = result.Score == 1
+		results[i] = result
+	}
+	return results
+}
+
+// boolScore returns full credit for true
+func boolScore(correct bool) float64 {
+	if correct {
+		return 1
+	}
+	return 0
+}
+
+// matchOptions maps submitted items (full options, letters or option
+// text) to the options they refer to, keeping unknown items as sent
+func matchOptions(options, items []string) []string {
+	matched := make([]string, len(items))
+	for j, item := range items {
+		matched[j] = item
+		if optionIndex(options, item) >= 0 {
+			continue
+		}
+		if k := matchAnswer(options, item); k >= 0 {
+			matched[j] = options[k]
+			continue
+		}
+		for _, option := range options {
+			if strings.EqualFold(option, item) {
+				matched[j] = option
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// multiSelectScore gives credit for each correct pick and takes it back
+// for each wrong one
+func multiSelectScore(correct, picked []string) float64 {
+	if len(correct) == 0 {
+		return 0
+	}
+	hits, misses := 0, 0
+	seen := make(map[string]bool, len(picked))
+	for _, item := range picked {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		if slices.Contains(correct, item) {
+			hits++
+		} else {
+			misses++
+		}
+	}
+	return math.Max(0, float64(hits-misses)/float64(len(correct)))
+}
+
+// orderingScore gives credit for each item in its correct position
+func orderingScore(correct, order []string) float64 {
+	if len(correct) == 0 {
+		return 0
+	}
+	inPlace := 0
+	for j, item := range order {
+		if j < len(correct) && correct[j] == item {
+			inPlace++
+		}
+	}
+	return float64(inPlace) / float64(len(correct))
+}
+
+// shortAnswerScore grades a free-text answer by the rubric keywords it
+// mentions as whole words; mentioning shortAnswerKeywordShare of them earns
+// full credit. Without keywords the answer must match the model answer.
+func shortAnswerScore(q models.QuizQuestion, answer string) float64 {
+	if answer == "" {
+		return 0
+	}
+	normalized := normalizeAnswerText(answer)
+	if len(q.Keywords) == 0 {
+		return boolScore(normalized == normalizeAnswerText(q.Answer))
+	}
+
+	// Padding makes " keyword " match whole words and phrases only
+	padded := " " + normalized + " "
+	mentioned := 0
+	for _, keyword := range q.Keywords {
+		if keyword = normalizeAnswerText(keyword); keyword != "" && strings.Contains(padded, " "+keyword+" ") {
+			mentioned++
+		}
+	}
+	needed := math.Ceil(float64(len(q.Keywords)) * shortAnswerKeywordShare)
+	score := math.Min(1, float64(mentioned)/needed)
+
+	maxWords := shortAnswerLengthFactor * len(strings.Fields(normalizeAnswerText(q.Answer)))
+	if maxWords < shortAnswerMinWords {
+		maxWords = shortAnswerMinWords
+	}
+	if words := len(strings.Fields(normalized)); words > maxWords {
+		score *= float64(maxWords) / float64(words)
+	}
+	return score
+}
+
+// normalizeAnswerText lowercases free text and drops punctuation and
+// repeated whitespace, so "Kemal," matches "kemal"
+func normalizeAnswerText(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+func textAnswer(text string) models.SubmittedAnswer {
+	return models.SubmittedAnswer{Values: []string{text}, IsString: true}
+}
+
+func listAnswer(items ...string) models.SubmittedAnswer {
+	return models.SubmittedAnswer{Values: items}
+}
+
+func TestScoreQuiz(t *testing.T) {
+	multipleChoice := models.QuizQuestion{
+		Type:    models.QuestionMultipleChoice,
+		Options: []string{"A) Paris", "B) Rome", "C) Oslo", "D) Bern"},
+		Answer:  "B) Rome",
+	}
+	legacy := multipleChoice
+	legacy.Type = ""
+	trueFalse := models.QuizQuestion{Type: models.QuestionTrueFalse, Answer: "true"}
+	multiSelect := models.QuizQuestion{
+		Type:    models.QuestionMultiSelect,
+		Options: []string{"A) Red", "B) Blue", "C) Green", "D) Yellow"},
+		Answers: []string{"A) Red", "C) Green"},
+	}
+	ordering := models.QuizQuestion{
+		Type:    models.QuestionOrdering,
+		Options: []string{"Third", "First", "Second"},
+		Answers: []string{"First", "Second", "Third"},
+	}
+	orderingCommas := models.QuizQuestion{
+		Type:    models.QuestionOrdering,
+		Options: []string{"Rome, Italy", "Paris, France", "Bern, Switzerland"},
+		Answers: []string{"Paris, France", "Rome, Italy", "Bern, Switzerland"},
+	}
+	fillBlank := models.QuizQuestion{
+		Type:     models.QuestionFillBlank,
+		Question: "Cumhuriyeti ___ ilan etti.",
+		Answer:   "Atatürk",
+		Answers:  []string{"Mustafa Kemal"},
+	}
+
+	tests := []struct {
+		name       string
+		question   models.QuizQuestion
+		answer     models.SubmittedAnswer
+		wantScore  float64
+		wantAnswer string
+	}{
+		{"multiple choice full option", multipleChoice, textAnswer("B) Rome"), 1, "B) Rome"},
+		{"multiple choice letter", multipleChoice, textAnswer("b)"), 1, "B) Rome"},
+		{"multiple choice text", multipleChoice, textAnswer("rome"), 1, "B) Rome"},
+		{"multiple choice wrong", multipleChoice, textAnswer("A"), 0, "A) Paris"},
+		{"multiple choice skipped", multipleChoice, models.SubmittedAnswer{}, 0, ""},
+		{"legacy question without type", legacy, textAnswer("B"), 1, "B) Rome"},
+		{"true/false", trueFalse, textAnswer("true"), 1, "true"},
+		{"true/false in Turkish", trueFalse, textAnswer("Doğru"), 1, "true"},
+		{"true/false wrong", trueFalse, textAnswer("yanlış"), 0, "false"},
+		{"true/false unreadable", trueFalse, textAnswer("belki"), 0, "belki"},
+		{"multi select list", multiSelect, listAnswer("A", "C"), 1, ""},
+		{"multi select string", multiSelect, textAnswer("A, c"), 1, ""},
+		{"multi select partial", multiSelect, listAnswer("A) Red"), 0.5, ""},
+		{"multi select repeated pick", multiSelect, listAnswer("A", "A"), 0.5, ""},
+		{"multi select wrong pick cancels a hit", multiSelect, listAnswer("A", "C", "D"), 0.5, ""},
+		{"multi select no credit below zero", multiSelect, listAnswer("B", "D"), 0, ""},
+		{"ordering", ordering, listAnswer("First", "Second", "Third"), 1, ""},
+		{"ordering string", ordering, textAnswer("first, second, third"), 1, ""},
+		{"ordering partial", ordering, listAnswer("First", "Third", "Second"), 0.33, ""},
+		{"ordering items with commas", orderingCommas, listAnswer("Paris, France", "Rome, Italy", "Bern, Switzerland"), 1, ""},
+		{"fill blank", fillBlank, textAnswer(" atatürk. "), 1, "atatürk."},
+		{"fill blank alternative", fillBlank, textAnswer("Mustafa  Kemal"), 1, "Mustafa  Kemal"},
+		{"fill blank wrong", fillBlank, textAnswer("İnönü"), 0, "İnönü"},
+	}
+	for _, tt := range tests {
+		results := ScoreQuiz([]models.QuizQuestion{tt.question}, []models.SubmittedAnswer{tt.answer})
+		if len(results) != 1 {
+			t.Fatalf("%s: ScoreQuiz() returned %d results, want 1", tt.name, len(results))
+		}
+		result := results[0]
+		if result.Score != tt.wantScore {
+			t.Errorf("%s: score = %v, want %v", tt.name, result.Score, tt.wantScore)
+		}
+		if result.Correct != (tt.wantScore == 1) {
+			t.Errorf("%s: correct = %v with score %v", tt.name, result.Correct, result.Score)
+		}
+		if result.Answer != tt.wantAnswer {
+			t.Errorf("%s: answer = %q, want %q", tt.name, result.Answer, tt.wantAnswer)
+		}
+		if result.Question != 1 || result.Type != tt.question.QuestionType() {
+			t.Errorf("%s: question = %d, type = %q", tt.name, result.Question, result.Type)
+		}
+	}
+}
+
+func TestShortAnswerScore(t *testing.T) {
+	keywords := models.QuizQuestion{
+		Type:     models.QuestionShortAnswer,
+		Answer:   "Floransa",
+		Keywords: []string{"Rönesans", "Floransa", "Medici", "yağlı boya"},
+	}
+	single := models.QuizQuestion{
+		Type:     models.QuestionShortAnswer,
+		Answer:   "Modern sanat müzesi",
+		Keywords: []string{"art"},
+	}
+	noKeywords := models.QuizQuestion{Type: models.QuestionShortAnswer, Answer: "Ankara"}
+
+	// 60 words against a 30 word limit halves the credit
+	long := "Floransa Medici " + strings.Repeat("kelime ", 58)
+
+	tests := []struct {
+		name     string
+		question models.QuizQuestion
+		answer   string
+		want     float64
+	}{
+		{"half the keywords", keywords, "Floransa ve Medici ailesi", 1},
+		{"one keyword", keywords, "Floransa'da", 0.5},
+		{"phrase keyword", keywords, "Bir yağlı boya tablo", 0.5},
+		{"phrase keyword split", keywords, "yağlı ve boya", 0},
+		{"no keyword", keywords, "Bilmiyorum", 0},
+		{"keyword inside a word", single, "There was a party", 0},
+		{"keyword as a word", single, "Modern art!", 1},
+		{"long answer", keywords, long, 0.5},
+		{"empty", keywords, "", 0},
+		{"model answer without keywords", noKeywords, "ankara.", 1},
+		{"wrong answer without keywords", noKeywords, "İstanbul", 0},
+	}
+	for _, tt := range tests {
+		if got := shortAnswerScore(tt.question, tt.answer); got != tt.want {
+			t.Errorf("%s: shortAnswerScore(%q) = %v, want %v", tt.name, tt.answer, got, tt.want)
+		}
+	}
+}
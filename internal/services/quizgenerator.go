@@ -3,65 +3,176 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/bookwise/api/config"
 	"github.com/bookwise/api/internal/models"
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
 )
 
-// QuizGeneratorService handles AI quiz generation using Google Gemini
+// QuizGeneratorService handles AI quiz generation through a QuizLLM backend
 type QuizGeneratorService struct {
-	client         *genai.Client
-	model          *genai.GenerativeModel
-	modelName      string
+	llm            QuizLLM
+	schema         *JSONSchema
+	questionMix    *QuestionMix
+	prompts        *PromptStore
 	questionsCount int
+	minQuality     int
 	retryLimit     int
+	timeout        time.Duration
+	budget         *LLMBudget
 }
 
-// NewQuizGeneratorService creates a new quiz generator service
-func NewQuizGeneratorService(cfg *config.Config) *QuizGeneratorService {
-	ctx := context.Background()
-	
-	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.Gemini.APIKey))
+// NewQuizGeneratorService creates a new quiz generator service using the
+// LLM backend selected in configuration
+func NewQuizGeneratorService(cfg *config.Config) (*QuizGeneratorService, error) {
+	llm, err := NewQuizLLMFromConfig(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create Gemini client: %v", err)
+		return nil, err
+	}
+
+	log.Printf("🤖 Quiz LLM: %s", modelID(llm))
+
+	return NewQuizGeneratorServiceWithLLM(llm, cfg)
+}
+
+// NewQuizGeneratorServiceWithLLM creates a quiz generator service around the
+// given backend, e.g. a FakeLLM in tests
+func NewQuizGeneratorServiceWithLLM(llm QuizLLM, cfg *config.Config) (*QuizGeneratorService, error) {
+	mix, err := NewQuestionMix(cfg.Quiz.QuestionTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts, err := NewPromptStore(cfg.Quiz.PromptDir)
+	if err != nil {
+		return nil, err
 	}
 
-	model := client.GenerativeModel(cfg.Gemini.Model)
-	
-	// Configure model for JSON output
-	model.SetTemperature(0.7)
-	model.SetTopK(40)
-	model.SetTopP(0.95)
-	model.ResponseMIMEType = "application/json"
-	
 	return &QuizGeneratorService{
-		client:         client,
-		model:          model,
-		modelName:      cfg.Gemini.Model,
+		llm:            llm,
+		schema:         NewQuizSchema(mix.Types(), 0),
+		questionMix:    mix,
+		prompts:        prompts,
 		questionsCount: cfg.Quiz.QuestionsCount,
+		minQuality:     cfg.Quiz.MinQualityScore,
 		retryLimit:     cfg.Quiz.RetryLimit,
+		timeout:        cfg.Quiz.LLMTimeout,
+		budget:         NewLLMBudget(cfg.Quiz.LLMDailyBudget),
+	}, nil
+}
+
+// Budget returns the daily LLM call budget shared by every generation
+func (s *QuizGeneratorService) Budget() *LLMBudget {
+	return s.budget
+}
+
+// Prompts returns the prompt templates used for generation
+func (s *QuizGeneratorService) Prompts() *PromptStore {
+	return s.prompts
+}
+
+// ModelID returns the "provider/model" identifier recorded in Quiz.AIModel
+func (s *QuizGeneratorService) ModelID() string {
+	return modelID(s.llm)
+}
+
+// ResolveQuizParams fills in the configured question count when params
+// leave it at 0
+func (s *QuizGeneratorService) ResolveQuizParams(params models.QuizParams) models.QuizParams {
+	if params.QuestionsCount == 0 {
+		params.QuestionsCount = s.questionsCount
+	}
+	return params
+}
+
+// GenerationError reports the error of every failed generateQuizAttempt
+// made by a single GenerateQuiz call
+type GenerationError struct {
+	Attempts []error
+}
+
+// Error implements the error interface
+func (e *GenerationError) Error() string {
+	return fmt.Sprintf("failed to generate quiz after %d attempts: %v", len(e.Attempts), e.Last())
+}
+
+// Unwrap returns the last attempt error
+func (e *GenerationError) Unwrap() error {
+	return e.Last()
+}
+
+// Last returns the error of the last attempt
+func (e *GenerationError) Last() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// Log formats every attempt error for Quiz.ErrorLog, listing validation
+// issues one per line
+func (e *GenerationError) Log() string {
+	var b strings.Builder
+	for i, err := range e.Attempts {
+		fmt.Fprintf(&b, "attempt %d: ", i+1)
+
+		var validationErr *QuizValidationError
+		if errors.As(err, &validationErr) {
+			b.WriteString("invalid quiz\n")
+			for _, line := range validationErr.Lines() {
+				fmt.Fprintf(&b, "  - %s\n", line)
+			}
+			continue
+		}
+
+		var qualityErr *QuizQualityError
+		if errors.As(err, &qualityErr) {
+			fmt.Fprintf(&b, "quality score %d below %d\n", qualityErr.Report.Score, qualityErr.MinScore)
+			for _, line := range qualityErr.Report.Lines() {
+				fmt.Fprintf(&b, "  - %s\n", line)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%v\n", err)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Messages returns the attempt errors as strings
+func (e *GenerationError) Messages() []string {
+	messages := make([]string, len(e.Attempts))
+	for i, err := range e.Attempts {
+		messages[i] = err.Error()
 	}
+	return messages
 }
 
-// GenerateQuiz generates a quiz for a given book with retry mechanism
-func (s *QuizGeneratorService) GenerateQuiz(book *models.Book) (*models.Quiz, error) {
-	var lastErr error
+// GenerateQuiz generates a quiz for a given book with retry mechanism.
+// params must be normalized (see NormalizeQuizParams) and are stored on
+// the quiz. When every attempt fails the returned error is a
+// *GenerationError; when the daily LLM budget runs out it is
+// ErrLLMBudgetExhausted.
+func (s *QuizGeneratorService) GenerateQuiz(book *models.Book, params models.QuizParams) (*models.Quiz, error) {
+	params = s.ResolveQuizParams(params)
+	genErr := &GenerationError{}
 	
 	for attempt := 1; attempt <= s.retryLimit; attempt++ {
-		log.Printf("🤖 Generating quiz for book '%s' (attempt %d/%d)", book.Title, attempt, s.retryLimit)
+		log.Printf("🤖 Generating %s quiz for book '%s' (attempt %d/%d)", params.Difficulty, book.Title, attempt, s.retryLimit)
 		
-		quiz, err := s.generateQuizAttempt(book)
+		quiz, err := s.generateQuizAttempt(book, params)
 		if err == nil {
 			log.Printf("✅ Quiz generated successfully for '%s'", book.Title)
 			return quiz, nil
 		}
+		if errors.Is(err, ErrLLMBudgetExhausted) {
+			return nil, err
+		}
 		
-		lastErr = err
+		genErr.Attempts = append(genErr.Attempts, err)
 		log.Printf("⚠️ Attempt %d failed: %v", attempt, err)
 		
 		if attempt < s.retryLimit {
@@ -72,88 +183,45 @@ func (s *QuizGeneratorService) GenerateQuiz(book *models.Book) (*models.Quiz, er
 		}
 	}
 	
-	return nil, fmt.Errorf("failed to generate quiz after %d attempts: %w", s.retryLimit, lastErr)
+	return nil, genErr
 }
 
 // generateQuizAttempt performs a single attempt to generate a quiz
-func (s *QuizGeneratorService) generateQuizAttempt(book *models.Book) (*models.Quiz, error) {
-	// Create book info for the prompt
-	bookInfo := map[string]interface{}{
-		"title":          book.Title,
-		"authors":        book.Authors,
-		"description":    book.Description,
-		"categories":     book.Categories,
-		"publisher":      book.Publisher,
-		"published_date": book.PublishedDate,
-	}
-	
-	bookInfoJSON, _ := json.MarshalIndent(bookInfo, "", "  ")
-	
-	// Create the prompt
-	prompt := fmt.Sprintf(`Kitabın bilgileri:
-%s
-
-Bu kitap hakkında %d adet çoktan seçmeli quiz sorusu oluştur.
-Sorular kitabın içeriği, teması, yazarı ve önemli noktaları hakkında olmalı.
-Her soru için 4 seçenek (A, B, C, D) sun ve doğru cevabı işaretle.
-Ayrıca her soru için kısa bir açıklama ekle.
-
-JSON formatında dön (başka bir şey yazma, sadece geçerli JSON):
-{
-  "quiz": [
-    {
-      "question": "soru metni",
-      "options": ["A) seçenek1", "B) seçenek2", "C) seçenek3", "D) seçenek4"],
-      "answer": "doğru cevap (ör: B) seçenek2)",
-      "explanation": "açıklama"
-    }
-  ]
-}
-
-ÖNEMLİ: Sadece JSON döndür, başka açıklama ekleme.`, string(bookInfoJSON), s.questionsCount)
-
-	// Call Gemini API
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-	
-	resp, err := s.model.GenerateContent(ctx, genai.Text(prompt))
+func (s *QuizGeneratorService) generateQuizAttempt(book *models.Book, params models.QuizParams) (*models.Quiz, error) {
+	prompt, promptVersion, err := s.prompts.Render(book, params, s.questionMix.Counts(params.QuestionsCount))
 	if err != nil {
-		return nil, fmt.Errorf("gemini api call failed: %w", err)
+		return nil, err
 	}
+
+	// Call the LLM backend
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
 	
-	if len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no response from gemini")
+	if err := s.budget.Reserve(); err != nil {
+		return nil, err
 	}
-	
-	if len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response from gemini")
+
+	schema := NewQuizSchema(s.questionMix.Types(), params.QuestionsCount)
+	content, err := s.llm.Generate(ctx, prompt, schema)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Extract text from response
-	content := fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-	
-	// Validate and parse JSON
-	var quizData models.QuizData
-	if err := json.Unmarshal([]byte(content), &quizData); err != nil {
-		return nil, fmt.Errorf("failed to parse quiz JSON: %w. Content: %s", err, content)
+	// Parse, repair and validate against the same schema the model was given
+	quizData, repairs, err := ParseQuiz(schema, []byte(content))
+	if err != nil {
+		return nil, err
 	}
-	
-	// Validate quiz structure
-	if len(quizData.Quiz) == 0 {
-		return nil, fmt.Errorf("quiz is empty")
+	if repairs > 0 {
+		log.Printf("🔧 Repaired %d issue(s) in generated quiz for '%s'", repairs, book.Title)
 	}
-	
-	for i, q := range quizData.Quiz {
-		if q.Question == "" {
-			return nil, fmt.Errorf("question %d is empty", i+1)
-		}
-		if len(q.Options) != 4 {
-			return nil, fmt.Errorf("question %d must have exactly 4 options, got %d", i+1, len(q.Options))
-		}
-		if q.Answer == "" {
-			return nil, fmt.Errorf("question %d has no answer", i+1)
-		}
+
+	// Score the quiz; a low score rejects it so it is generated again
+	report := CheckQuizQuality(quizData.Quiz)
+	if report.Score < s.minQuality {
+		return nil, &QuizQualityError{Report: report, MinScore: s.minQuality}
 	}
+	log.Printf("🧪 Quiz quality score for '%s': %d (%d issue(s))", book.Title, report.Score, len(report.Issues))
 	
 	// Create quiz model
 	questionsJSON, err := json.Marshal(quizData.Quiz)
@@ -164,44 +232,34 @@ JSON formatında dön (başka bir şey yazma, sadece geçerli JSON):
 	quiz := &models.Quiz{
 		BookID:     book.ID,
 		Questions:  questionsJSON,
-		AIModel:    s.modelName,
+		AIModel:    s.ModelID(),
 		Status:     "completed",
 		RetryCount: 0,
+		QuizParams: params,
+
+		PromptVersion: promptVersion,
+		QualityScore:  &report.Score,
+		QualityIssues: report.Lines(),
 	}
 	
 	return quiz, nil
 }
 
-// ValidateQuizJSON validates if the quiz JSON is properly formatted
+// ValidateQuizJSON validates if the quiz JSON is properly formatted.
+// Unlike generation it does not repair anything.
 func (s *QuizGeneratorService) ValidateQuizJSON(data []byte) error {
-	var quizData models.QuizData
-	if err := json.Unmarshal(data, &quizData); err != nil {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
 		return fmt.Errorf("invalid JSON format: %w", err)
 	}
-	
-	if len(quizData.Quiz) == 0 {
-		return fmt.Errorf("quiz cannot be empty")
-	}
-	
-	for i, q := range quizData.Quiz {
-		if q.Question == "" {
-			return fmt.Errorf("question %d: question text is required", i+1)
-		}
-		if len(q.Options) != 4 {
-			return fmt.Errorf("question %d: must have exactly 4 options", i+1)
-		}
-		if q.Answer == "" {
-			return fmt.Errorf("question %d: answer is required", i+1)
-		}
-		if q.Explanation == "" {
-			return fmt.Errorf("question %d: explanation is required", i+1)
-		}
+
+	if issues := validateQuiz(s.schema, value); len(issues) > 0 {
+		return &QuizValidationError{Issues: issues}
 	}
-	
 	return nil
 }
 
-// Close closes the Gemini client
+// Close closes the LLM backend
 func (s *QuizGeneratorService) Close() error {
-	return s.client.Close()
+	return s.llm.Close()
 }
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/models"
+)
+
+// staticLLM returns the same response to every prompt
+type staticLLM struct {
+	content string
+}
+
+func (l *staticLLM) Provider() string { return "static" }
+func (l *staticLLM) Model() string    { return "test" }
+func (l *staticLLM) Close() error     { return nil }
+
+func (l *staticLLM) Generate(ctx context.Context, prompt string, schema *JSONSchema) (string, error) {
+	return l.content, nil
+}
+
+// newTestQuizGenerator creates a generator with a single attempt per quiz,
+// so failures don't wait for the retry backoff, and no LLM budget, so no
+// database is needed
+func newTestQuizGenerator(t *testing.T, llm QuizLLM) *QuizGeneratorService {
+	t.Helper()
+	cfg := &config.Config{Quiz: config.QuizConfig{
+		QuestionsCount: 6,
+		RetryLimit:     1,
+		LLMTimeout:     5 * time.Second,
+		QuestionTypes: map[string]int{
+			models.QuestionMultipleChoice: 1, models.QuestionTrueFalse: 1, models.QuestionMultiSelect: 1,
+			models.QuestionOrdering: 1, models.QuestionFillBlank: 1, models.QuestionShortAnswer: 1,
+		},
+		MinQualityScore: 80,
+		PromptDir:       "../../prompts",
+	}}
+	generator, err := NewQuizGeneratorServiceWithLLM(llm, cfg)
+	if err != nil {
+		t.Fatalf("NewQuizGeneratorServiceWithLLM() error: %v", err)
+	}
+	return generator
+}
+
+func testBook() *models.Book {
+	return &models.Book{
+		Title:       "Introduction to Algorithms",
+		Authors:     []string{"Thomas H. Cormen", "Charles E. Leiserson"},
+		ISBN:        "0262033844",
+		ISBN13:      "9780262033848",
+		Description: "A comprehensive introduction to the modern study of computer algorithms.",
+		Categories:  []string{"Computers"},
+		Language:    "en",
+	}
+}
+
+func TestGenerateQuiz(t *testing.T) {
+	tests := []struct {
+		name   string
+		params models.QuizParams
+		want   int
+	}{
+		{"configured count", models.QuizParams{Difficulty: models.DifficultyMedium, Language: "tr"}, 6},
+		{"requested count", models.QuizParams{Difficulty: models.DifficultyHard, Language: "en", QuestionsCount: 3}, 3},
+	}
+	for _, tt := range tests {
+		generator := newTestQuizGenerator(t, NewFakeLLM(10))
+		quiz, err := generator.GenerateQuiz(testBook(), tt.params)
+		if err != nil {
+			t.Fatalf("%s: GenerateQuiz() error: %v", tt.name, err)
+		}
+
+		var questions []models.QuizQuestion
+		if err := json.Unmarshal(quiz.Questions, &questions); err != nil {
+			t.Fatalf("%s: stored questions don't decode: %v", tt.name, err)
+		}
+		if len(questions) != tt.want || quiz.QuestionsCount != tt.want {
+			t.Errorf("%s: got %d questions (params %d), want %d", tt.name, len(questions), quiz.QuestionsCount, tt.want)
+		}
+		types := make(map[string]bool)
+		for _, question := range questions {
+			types[question.QuestionType()] = true
+		}
+		if len(types) != tt.want {
+			t.Errorf("%s: got question types %v, want %d distinct types", tt.name, types, tt.want)
+		}
+
+		if quiz.Status != "completed" || quiz.AIModel != "fake/deterministic" {
+			t.Errorf("%s: status = %q, model = %q", tt.name, quiz.Status, quiz.AIModel)
+		}
+		if quiz.Difficulty != tt.params.Difficulty || quiz.Language != tt.params.Language {
+			t.Errorf("%s: params = %+v, want %+v", tt.name, quiz.QuizParams, tt.params)
+		}
+		if !strings.HasPrefix(quiz.PromptVersion, "quiz."+tt.params.Language+"@") {
+			t.Errorf("%s: prompt version = %q", tt.name, quiz.PromptVersion)
+		}
+		if quiz.QualityScore == nil || *quiz.QualityScore < 80 {
+			t.Errorf("%s: quality score = %v, issues %q", tt.name, quiz.QualityScore, quiz.QualityIssues)
+		}
+
+		// The same book and params yield the same quiz
+		again, err := generator.GenerateQuiz(testBook(), tt.params)
+		if err != nil || string(again.Questions) != string(quiz.Questions) {
+			t.Errorf("%s: second GenerateQuiz() differs (error %v)", tt.name, err)
+		}
+	}
+}
+
+func TestGenerateQuizFailures(t *testing.T) {
+	duplicate := `{"type": "true_false", "question": "The book covers sorting algorithms.", "answer": "true", "explanation": "It does"}`
+
+	tests := []struct {
+		name    string
+		llm     QuizLLM
+		wantErr func(err error) bool
+	}{
+		{
+			name: "backend error",
+			llm:  &FakeLLM{questionsCount: 1, Err: errors.New("quota exceeded")},
+			wantErr: func(err error) bool {
+				return strings.Contains(err.Error(), "quota exceeded")
+			},
+		},
+		{
+			name: "invalid quiz",
+			llm:  &staticLLM{content: `{"quiz": [` + duplicate + `]}`},
+			wantErr: func(err error) bool {
+				var validationErr *QuizValidationError
+				return errors.As(err, &validationErr)
+			},
+		},
+		{
+			name: "low quality",
+			llm:  &staticLLM{content: `{"quiz": [` + duplicate + `, ` + duplicate + `, ` + duplicate + `]}`},
+			wantErr: func(err error) bool {
+				var qualityErr *QuizQualityError
+				return errors.As(err, &qualityErr) && qualityErr.Report.Score == 60 && qualityErr.MinScore == 80
+			},
+		},
+		{
+			name: "unknown language",
+			llm:  NewFakeLLM(3),
+			wantErr: func(err error) bool {
+				return strings.Contains(err.Error(), `no quiz prompt template for language "de"`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		generator := newTestQuizGenerator(t, tt.llm)
+		params := models.QuizParams{Difficulty: models.DifficultyMedium, Language: "en", QuestionsCount: 3}
+		if tt.name == "unknown language" {
+			params.Language = "de"
+		}
+
+		quiz, err := generator.GenerateQuiz(testBook(), params)
+		if err == nil {
+			t.Errorf("%s: GenerateQuiz() = %+v, want error", tt.name, quiz)
+			continue
+		}
+		var genErr *GenerationError
+		if !errors.As(err, &genErr) || len(genErr.Attempts) != 1 {
+			t.Errorf("%s: error = %v, want a GenerationError with one attempt", tt.name, err)
+			continue
+		}
+		if !tt.wantErr(err) {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
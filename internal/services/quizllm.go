@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bookwise/api/config"
+)
+
+// Quiz LLM provider names (QUIZ_LLM_PROVIDER)
+const (
+	QuizLLMGemini = "gemini"
+	QuizLLMOpenAI = "openai"
+	QuizLLMFake   = "fake"
+)
+
+// QuizLLM is a language model backend used to generate quizzes.
+// Generate receives the full prompt and the expected response schema and
+// returns the raw JSON text produced by the model; backends pass the schema
+// on when the API supports structured output. Parsing and validation are
+// done by QuizGeneratorService.
+type QuizLLM interface {
+	// Provider returns the backend name (e.g. "gemini")
+	Provider() string
+	// Model returns the model used by the backend
+	Model() string
+
+	Generate(ctx context.Context, prompt string, schema *JSONSchema) (string, error)
+	Close() error
+}
+
+// Compile-time checks that built-in backends implement QuizLLM
+var (
+	_ QuizLLM = (*GeminiLLM)(nil)
+	_ QuizLLM = (*OpenAILLM)(nil)
+	_ QuizLLM = (*FakeLLM)(nil)
+)
+
+// NewQuizLLMFromConfig builds the backend selected by cfg.Quiz.LLMProvider
+func NewQuizLLMFromConfig(cfg *config.Config) (QuizLLM, error) {
+	switch cfg.Quiz.LLMProvider {
+	case QuizLLMGemini:
+		return NewGeminiLLM(cfg.Gemini.APIKey, cfg.Gemini.Model)
+	case QuizLLMOpenAI:
+		return NewOpenAILLM(cfg.OpenAI.BaseURL, cfg.OpenAI.APIKey, cfg.OpenAI.Model)
+	case QuizLLMFake:
+		return NewFakeLLM(cfg.Quiz.QuestionsCount), nil
+	default:
+		return nil, fmt.Errorf("unknown quiz LLM provider %q", cfg.Quiz.LLMProvider)
+	}
+}
+
+// modelID returns the "provider/model" identifier stored in Quiz.AIModel
+func modelID(llm QuizLLM) string {
+	return llm.Provider() + "/" + llm.Model()
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// maxQuizQuestions caps the question count of a single quiz
+const maxQuizQuestions = 20
+
+// ErrInvalidQuizParams is returned for unknown difficulties, age groups or
+// focus areas and out of range question counts
+var ErrInvalidQuizParams = errors.New("invalid quiz parameters")
+
+// NormalizeQuizParams validates quiz parameters and fills in defaults:
+// medium difficulty and focus areas sorted without duplicates. A question
+// count of 0 stays 0 and means QUIZ_QUESTIONS_COUNT; an empty language
+// stays empty and is resolved from the book when the job is enqueued.
+func NormalizeQuizParams(params models.QuizParams) (models.QuizParams, error) {
+	params.Difficulty = strings.ToLower(strings.TrimSpace(params.Difficulty))
+	if params.Difficulty == "" {
+		params.Difficulty = models.DifficultyMedium
+	}
+	if !slices.Contains(models.Difficulties, params.Difficulty) {
+		return params, fmt.Errorf("%w: difficulty must be one of: %s", ErrInvalidQuizParams, strings.Join(models.Difficulties, ", "))
+	}
+
+	if params.QuestionsCount < 0 || params.QuestionsCount > maxQuizQuestions {
+		return params, fmt.Errorf("%w: questions_count must be between 1 and %d", ErrInvalidQuizParams, maxQuizQuestions)
+	}
+
+	if params.Language != "" {
+		language := NormalizeLanguage(params.Language)
+		if !slices.Contains(models.QuizLanguages, language) {
+			return params, fmt.Errorf("%w: language must be one of: %s", ErrInvalidQuizParams, strings.Join(models.QuizLanguages, ", "))
+		}
+		params.Language = language
+	}
+
+	params.AgeGroup = strings.ToLower(strings.TrimSpace(params.AgeGroup))
+	if params.AgeGroup != "" && !slices.Contains(models.AgeGroups, params.AgeGroup) {
+		return params, fmt.Errorf("%w: age_group must be one of: %s", ErrInvalidQuizParams, strings.Join(models.AgeGroups, ", "))
+	}
+
+	focus := pq.StringArray{}
+	for _, area := range params.Focus {
+		area = strings.ToLower(strings.TrimSpace(area))
+		if !slices.Contains(models.QuizFocuses, area) {
+			return params, fmt.Errorf("%w: focus must be one of: %s", ErrInvalidQuizParams, strings.Join(models.QuizFocuses, ", "))
+		}
+		if !slices.Contains(focus, area) {
+			focus = append(focus, area)
+		}
+	}
+	slices.Sort(focus)
+	params.Focus = focus
+
+	return params, nil
+}
+
+// NormalizeLanguage turns a language tag ("en-US", "EN", "eng") into its
+// lowercase ISO 639-1 code
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) == 3 {
+		for code, marc := range marcLanguages {
+			if marc == tag {
+				return code
+			}
+		}
+	}
+	return tag
+}
+
+// ResolveQuizLanguage returns the first candidate language quizzes can be
+// generated in, e.g. from Accept-Language and then Book.Language, falling
+// back to Turkish
+func ResolveQuizLanguage(candidates ...string) string {
+	for _, candidate := range candidates {
+		if language := NormalizeLanguage(candidate); slices.Contains(models.QuizLanguages, language) {
+			return language
+		}
+	}
+	return models.LanguageTurkish
+}
+
+// FindQuiz returns the newest completed quiz of the book generated with
+// exactly these (normalized, count and language resolved) parameters
+func FindQuiz(bookID uuid.UUID, params models.QuizParams) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := database.DB.Where("book_id = ? AND status = ? AND difficulty = ? AND language = ? AND questions_count = ? AND COALESCE(age_group, '') = ? AND COALESCE(focus, '{}') = ?",
+		bookID, "completed", params.Difficulty, params.Language, params.QuestionsCount, params.AgeGroup, params.Focus).
+		Order("version DESC").
+		First(&quiz).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// quizVariant narrows a quiz or job query to a difficulty and language;
+// empty values match any
+func quizVariant(query *gorm.DB, difficulty, language string) *gorm.DB {
+	if difficulty != "" {
+		query = query.Where("difficulty = ?", difficulty)
+	}
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	return query
+}
+
+// FindBookQuiz returns the book's quiz for a difficulty and language (empty
+// for any): the active quiz when it matches, otherwise the newest
+// completed one
+func FindBookQuiz(book *models.Book, difficulty, language string) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if book.QuizID != nil {
+		err := quizVariant(database.DB.Where("id = ?", *book.QuizID), difficulty, language).First(&quiz).Error
+		if err == nil {
+			return &quiz, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	err := quizVariant(database.DB.Where("book_id = ? AND status = ?", book.ID, "completed"), difficulty, language).
+		Order("version DESC").
+		First(&quiz).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
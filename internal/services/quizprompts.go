@@ -0,0 +1,193 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+// QuizPromptData is what quiz prompt templates are rendered with
+type QuizPromptData struct {
+	Book           *models.Book
+	BookJSON       string // Title, authors, description, categories, publisher and published date as indented JSON
+	QuestionsCount int
+	Language       string
+	Difficulty     string
+	AgeGroup       string   // Empty: no specific audience
+	Focus          []string // Empty: every aspect of the book
+	QuestionTypes  []QuestionTypeCount
+}
+
+// newQuizPromptData collects the template variables for a book and the
+// (resolved) quiz parameters
+func newQuizPromptData(book *models.Book, params models.QuizParams, counts []QuestionTypeCount) *QuizPromptData {
+	bookInfo := map[string]interface{}{
+		"title":          book.Title,
+		"authors":        book.Authors,
+		"description":    book.Description,
+		"categories":     book.Categories,
+		"publisher":      book.Publisher,
+		"published_date": book.PublishedDate,
+	}
+	bookInfoJSON, _ := json.MarshalIndent(bookInfo, "", "  ")
+
+	return &QuizPromptData{
+		Book:           book,
+		BookJSON:       string(bookInfoJSON),
+		QuestionsCount: params.QuestionsCount,
+		Language:       params.Language,
+		Difficulty:     params.Difficulty,
+		AgeGroup:       params.AgeGroup,
+		Focus:          params.Focus,
+		QuestionTypes:  counts,
+	}
+}
+
+// PromptTemplate is a loaded quiz prompt template for one language
+type PromptTemplate struct {
+	Language string
+	Version  string // "quiz.<language>@<sha256 prefix of the file>", stored on generated quizzes
+	tmpl     *template.Template
+}
+
+// PromptStore holds the quiz prompt templates of every quiz language,
+// loaded from quiz.<language>.tmpl files in a directory
+// (QUIZ_PROMPT_DIR). Reload swaps in edited files at runtime.
+type PromptStore struct {
+	dir       string
+	mu        sync.RWMutex
+	templates map[string]*PromptTemplate
+}
+
+// NewPromptStore loads the prompt templates in dir
+func NewPromptStore(dir string) (*PromptStore, error) {
+	store := &PromptStore{dir: dir}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Reload loads the templates again. If any template is missing or fails
+// to render, the templates in use are kept and the error is returned.
+func (s *PromptStore) Reload() error {
+	templates := make(map[string]*PromptTemplate, len(models.QuizLanguages))
+	for _, language := range models.QuizLanguages {
+		prompt, err := loadPromptTemplate(s.dir, language)
+		if err != nil {
+			return err
+		}
+		templates[language] = prompt
+	}
+
+	s.mu.Lock()
+	changed := make([]string, 0, len(templates))
+	for language, prompt := range templates {
+		if current := s.templates[language]; current == nil || current.Version != prompt.Version {
+			changed = append(changed, prompt.Version)
+		}
+	}
+	s.templates = templates
+	s.mu.Unlock()
+
+	sort.Strings(changed)
+	if len(changed) > 0 {
+		log.Printf("📝 Quiz prompt templates loaded from %s: %s", s.dir, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// Render renders the prompt for a book in the language of params and
+// returns it with the template version
+func (s *PromptStore) Render(book *models.Book, params models.QuizParams, counts []QuestionTypeCount) (string, string, error) {
+	s.mu.RLock()
+	prompt := s.templates[params.Language]
+	s.mu.RUnlock()
+	if prompt == nil {
+		return "", "", fmt.Errorf("no quiz prompt template for language %q", params.Language)
+	}
+
+	text, err := prompt.render(newQuizPromptData(book, params, counts))
+	if err != nil {
+		return "", "", err
+	}
+	return text, prompt.Version, nil
+}
+
+// Versions returns the version of the template in use for each language
+func (s *PromptStore) Versions() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := make(map[string]string, len(s.templates))
+	for language, prompt := range s.templates {
+		versions[language] = prompt.Version
+	}
+	return versions
+}
+
+// loadPromptTemplate parses quiz.<language>.tmpl and checks that it renders
+// every question type
+func loadPromptTemplate(dir, language string) (*PromptTemplate, error) {
+	name := fmt.Sprintf("quiz.%s", language)
+	path := filepath.Join(dir, name+".tmpl")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quiz prompt template: %w", err)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiz prompt template %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(content)
+	prompt := &PromptTemplate{
+		Language: language,
+		Version:  name + "@" + hex.EncodeToString(sum[:])[:12],
+		tmpl:     tmpl,
+	}
+
+	// Render a sample for every question type so a broken edit is rejected
+	// at load time rather than failing generations
+	book := &models.Book{Title: "Sample", Authors: []string{"Author"}}
+	for _, questionType := range models.QuestionTypes {
+		params := models.QuizParams{
+			Difficulty:     models.DifficultyMedium,
+			Language:       language,
+			QuestionsCount: 5,
+			AgeGroup:       models.AgeGroupAdult,
+			Focus:          models.QuizFocuses,
+		}
+		text, err := prompt.render(newQuizPromptData(book, params, []QuestionTypeCount{{Type: questionType, Count: 5}}))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiz prompt template %s: %w", path, err)
+		}
+		if !strings.Contains(text, questionType) {
+			return nil, fmt.Errorf("invalid quiz prompt template %s: question type %q is not described", path, questionType)
+		}
+	}
+
+	return prompt, nil
+}
+
+// render executes the template; surrounding whitespace is trimmed
+func (p *PromptTemplate) render(data *QuizPromptData) (string, error) {
+	var b bytes.Buffer
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render quiz prompt %s: %w", p.Version, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+// Quality checks run on every generated quiz
+const (
+	QualityDuplicateQuestion = "duplicate_question" // Two questions ask the same thing
+	QualitySimilarOptions    = "similar_options"    // Two options of a question are near-identical
+	QualityOptionLengthBias  = "option_length_bias" // The correct option is usually the longest one
+	QualityAnswerPosition    = "answer_position"    // The correct option is usually at the same position
+	QualityBannedPattern     = "banned_pattern"     // "All of the above", page count questions, ...
+)
+
+const (
+	duplicateQuestionSimilarity = 0.85 // Bigram similarity from which questions are duplicates
+	similarOptionSimilarity     = 0.9  // Bigram similarity from which options are near-identical
+	lengthBiasRatio             = 1.5  // Correct option this much longer than the others' average
+	lengthBiasShare             = 0.5  // Share of multiple choice questions with a length biased answer
+	answerPositionShare         = 0.75 // Share of multiple choice answers at one position
+
+	duplicateQuestionPenalty = 20
+	similarOptionsPenalty    = 10
+	optionLengthBiasPenalty  = 15
+	answerPositionPenalty    = 15
+	bannedPatternPenalty     = 20
+)
+
+// Banned patterns are matched as whole words against normalizeAnswerText
+// output.
+var (
+	// bannedOptionPatterns are catch-all options that make the answer
+	// guessable. Only full phrases are listed: bare words such as "hepsi"
+	// also appear in legitimate options.
+	bannedOptionPatterns = []string{
+		"all of the above",
+		"none of the above",
+		"both a and b",
+		"yukarıdakilerin hepsi",
+		"yukarıdakilerin hiçbiri",
+		"a ve b",
+	}
+
+	// bannedQuestionPatterns ask about book metadata rather than content
+	bannedQuestionPatterns = []string{
+		"how many pages",
+		"number of pages",
+		"page count",
+		"kaç sayfa",
+		"sayfa sayısı",
+		"isbn",
+		"which publisher",
+		"hangi yayınevi",
+	}
+)
+
+// QualityIssue is a problem a quality check found in a quiz
+type QualityIssue struct {
+	Check    string
+	Question int // 1-based; 0 for the quiz as a whole
+	Message  string
+	Penalty  int
+}
+
+// QualityReport is the outcome of the quality checks of a quiz
+type QualityReport struct {
+	Score  int // 100 minus the penalties of the issues, at least 0
+	Issues []QualityIssue
+}
+
+// Lines formats the issues one per line
+func (r *QualityReport) Lines() []string {
+	lines := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		if issue.Question > 0 {
+			lines[i] = fmt.Sprintf("%s: question %d: %s (-%d)", issue.Check, issue.Question, issue.Message, issue.Penalty)
+		} else {
+			lines[i] = fmt.Sprintf("%s: %s (-%d)", issue.Check, issue.Message, issue.Penalty)
+		}
+	}
+	return lines
+}
+
+// QuizQualityError is returned for a generated quiz whose quality score is
+// below QUIZ_MIN_QUALITY_SCORE, so it is generated again
+type QuizQualityError struct {
+	Report   *QualityReport
+	MinScore int
+}
+
+// Error implements the error interface
+func (e *QuizQualityError) Error() string {
+	return fmt.Sprintf("quiz quality score %d is below %d: %s", e.Report.Score, e.MinScore, strings.Join(e.Report.Lines(), "; "))
+}
+
+// qualityCheck is one step of the quality pipeline
+type qualityCheck func(questions []models.QuizQuestion) []QualityIssue
+
+// qualityChecks run in order on every generated quiz
+var qualityChecks = []qualityCheck{
+	checkDuplicateQuestions,
+	checkSimilarOptions,
+	checkOptionLengthBias,
+	checkAnswerPositions,
+	checkBannedPatterns,
+}
+
+// CheckQuizQuality runs the quality checks on validated questions and
+// scores the quiz
+func CheckQuizQuality(questions []models.QuizQuestion) *QualityReport {
+	report := &QualityReport{Score: 100}
+	for _, check := range qualityChecks {
+		for _, issue := range check(questions) {
+			report.Issues = append(report.Issues, issue)
+			report.Score -= issue.Penalty
+		}
+	}
+	if report.Score < 0 {
+		report.Score = 0
+	}
+	return report
+}
+
+// checkDuplicateQuestions flags questions similar to an earlier one
+func checkDuplicateQuestions(questions []models.QuizQuestion) []QualityIssue {
+	var issues []QualityIssue
+	for i := range questions {
+		for j := 0; j < i; j++ {
+			if textSimilarity(questions[i].Question, questions[j].Question) >= duplicateQuestionSimilarity {
+				issues = append(issues, QualityIssue{
+					Check:    QualityDuplicateQuestion,
+					Question: i + 1,
+					Message:  fmt.Sprintf("duplicates question %d", j+1),
+					Penalty:  duplicateQuestionPenalty,
+				})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// checkSimilarOptions flags questions with two near-identical options
+func checkSimilarOptions(questions []models.QuizQuestion) []QualityIssue {
+	var issues []QualityIssue
+	for i, question := range questions {
+		options := optionTexts(question.Options)
+	pairs:
+		for a := range options {
+			for b := 0; b < a; b++ {
+				if textSimilarity(options[a], options[b]) >= similarOptionSimilarity {
+					issues = append(issues, QualityIssue{
+						Check:    QualitySimilarOptions,
+						Question: i + 1,
+						Message:  fmt.Sprintf("options %d and %d are near-identical", b+1, a+1),
+						Penalty:  similarOptionsPenalty,
+					})
+					break pairs
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// checkOptionLengthBias flags quizzes where the correct option of most
+// multiple choice questions stands out as the longest
+func checkOptionLengthBias(questions []models.QuizQuestion) []QualityIssue {
+	total, biased := 0, 0
+	for _, question := range questions {
+		answer := optionIndex(question.Options, question.Answer)
+		if question.QuestionType() != models.QuestionMultipleChoice || answer < 0 || len(question.Options) < 2 {
+			continue
+		}
+		total++
+
+		options := optionTexts(question.Options)
+		longest := true
+		others := 0
+		for j, option := range options {
+			if j == answer {
+				continue
+			}
+			others += utf8.RuneCountInString(option)
+			if utf8.RuneCountInString(option) >= utf8.RuneCountInString(options[answer]) {
+				longest = false
+			}
+		}
+		average := float64(others) / float64(len(options)-1)
+		if longest && float64(utf8.RuneCountInString(options[answer])) >= lengthBiasRatio*average {
+			biased++
+		}
+	}
+
+	if biased < 2 || float64(biased) < lengthBiasShare*float64(total) {
+		return nil
+	}
+	return []QualityIssue{{
+		Check:   QualityOptionLengthBias,
+		Message: fmt.Sprintf("the correct option is clearly the longest in %d of %d multiple choice questions", biased, total),
+		Penalty: optionLengthBiasPenalty,
+	}}
+}
+
+// checkAnswerPositions flags quizzes where most multiple choice answers are
+// at the same option position
+func checkAnswerPositions(questions []models.QuizQuestion) []QualityIssue {
+	counts := make([]int, len(quizOptionLetters))
+	total := 0
+	for _, question := range questions {
+		answer := optionIndex(question.Options, question.Answer)
+		if question.QuestionType() != models.QuestionMultipleChoice || answer < 0 || answer >= len(counts) {
+			continue
+		}
+		counts[answer]++
+		total++
+	}
+	if total < 3 {
+		return nil
+	}
+
+	for position, count := range counts {
+		if count >= 3 && float64(count) >= answerPositionShare*float64(total) {
+			return []QualityIssue{{
+				Check:   QualityAnswerPosition,
+				Message: fmt.Sprintf("%d of %d multiple choice answers are option %s", count, total, quizOptionLetters[position]),
+				Penalty: answerPositionPenalty,
+			}}
+		}
+	}
+	return nil
+}
+
+// checkBannedPatterns flags metadata questions and questions with a
+// catch-all option
+func checkBannedPatterns(questions []models.QuizQuestion) []QualityIssue {
+	var issues []QualityIssue
+	for i, question := range questions {
+		pattern := findBannedPattern([]string{question.Question}, bannedQuestionPatterns)
+		if pattern == "" {
+			pattern = findBannedPattern(optionTexts(question.Options), bannedOptionPatterns)
+		}
+		if pattern != "" {
+			issues = append(issues, QualityIssue{
+				Check:    QualityBannedPattern,
+				Question: i + 1,
+				Message:  fmt.Sprintf("contains %q", pattern),
+				Penalty:  bannedPatternPenalty,
+			})
+		}
+	}
+	return issues
+}
+
+// findBannedPattern returns the first of patterns found in texts, or ""
+func findBannedPattern(texts, patterns []string) string {
+	for _, text := range texts {
+		normalized := " " + normalizeAnswerText(text) + " "
+		for _, pattern := range patterns {
+			if strings.Contains(normalized, " "+pattern+" ") {
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// optionTexts returns the options without their "A) " prefixes
+func optionTexts(options []string) []string {
+	texts := make([]string, len(options))
+	for j, option := range options {
+		texts[j] = option
+		if _, rest, ok := splitOption(option); ok {
+			texts[j] = rest
+		}
+	}
+	return texts
+}
+
+// textSimilarity is the Dice coefficient of the character bigrams of the
+// normalized texts: 1 for equal texts, near 0 for unrelated ones
+func textSimilarity(a, b string) float64 {
+	a, b = normalizeAnswerText(a), normalizeAnswerText(b)
+	if a == b {
+		return 1
+	}
+
+	bigramsA, bigramsB := textBigrams(a), textBigrams(b)
+	if len(bigramsA) == 0 || len(bigramsB) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(bigramsA))
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+	shared := 0
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(bigramsA)+len(bigramsB))
+}
+
+// textBigrams returns the overlapping two-character pieces of text
+func textBigrams(text string) []string {
+	runes := []rune(text)
+	if len(runes) < 2 {
+		return nil
+	}
+	bigrams := make([]string, len(runes)-1)
+	for i := range bigrams {
+		bigrams[i] = string(runes[i : i+2])
+	}
+	return bigrams
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+// testQuizQuestions returns a quiz that passes every quality check
+func testQuizQuestions() []models.QuizQuestion {
+	multipleChoice := func(question string, answer int, options ...string) models.QuizQuestion {
+		for j := range options {
+			options[j] = quizOptionLetters[j] + ") " + options[j]
+		}
+		return models.QuizQuestion{
+			Type:     models.QuestionMultipleChoice,
+			Question: question,
+			Options:  options,
+			Answer:   options[answer],
+		}
+	}
+	return []models.QuizQuestion{
+		multipleChoice("Romanın baş karakteri kimdir?", 0, "Ahmet", "Mehmet", "Ayşe", "Fatma"),
+		multipleChoice("Hikaye hangi şehirde başlar?", 1, "İstanbul", "Ankara", "İzmir", "Bursa"),
+		multipleChoice("Yazarın ana teması nedir?", 2, "Aşk", "Savaş", "Göç", "Yalnızlık"),
+		multipleChoice("Olaylar hangi dönemde yaşanır?", 3, "Ortaçağ", "Rönesans", "Sanayi devri", "Günümüz"),
+		{Type: models.QuestionTrueFalse, Question: "Yazar romanı sürgünde yazmıştır.", Answer: "true"},
+	}
+}
+
+func TestCheckQuizQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(questions []models.QuizQuestion) []models.QuizQuestion
+		wantIssues []string // "check:question"
+		wantScore  int
+	}{
+		{
+			name:      "clean",
+			modify:    func(q []models.QuizQuestion) []models.QuizQuestion { return q },
+			wantScore: 100,
+		},
+		{
+			name: "duplicate question",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				duplicate := q[1]
+				duplicate.Question = "Hikaye hangi şehirde başlar"
+				return append(q, duplicate)
+			},
+			wantIssues: []string{"duplicate_question:6"},
+			wantScore:  80,
+		},
+		{
+			name: "similar options",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				q[1].Options[2] = "C) izmir."
+				q[1].Options[3] = "D) İzmir"
+				return q
+			},
+			wantIssues: []string{"similar_options:2"},
+			wantScore:  90,
+		},
+		{
+			name: "longest option is the answer",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				q[0].Options[0] = "A) Ahmet, kasabanın en yaşlı öğretmeni"
+				q[0].Answer = q[0].Options[0]
+				q[1].Options[1] = "B) Ankara'nın eski mahallelerinden Ulus"
+				q[1].Answer = q[1].Options[1]
+				return q
+			},
+			wantIssues: []string{"option_length_bias:0"},
+			wantScore:  85,
+		},
+		{
+			name: "answers at one position",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				for i := 0; i < 4; i++ {
+					q[i].Answer = q[i].Options[0]
+				}
+				return q
+			},
+			wantIssues: []string{"answer_position:0"},
+			wantScore:  85,
+		},
+		{
+			name: "metadata question",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				q[2].Question = "How many pages does the book have?"
+				q[3].Question = "Kitabın ISBN numarası nedir?"
+				return q
+			},
+			wantIssues: []string{"banned_pattern:3", "banned_pattern:4"},
+			wantScore:  60,
+		},
+		{
+			name: "catch-all option",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				q[3].Options[2] = "C) Yukarıdakilerin hepsi"
+				return q
+			},
+			wantIssues: []string{"banned_pattern:4"},
+			wantScore:  80,
+		},
+		{
+			name: "legitimate option mentioning hepsi",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				q[2].Options[1] = "B) Hepsi aynı gün doğar"
+				q[2].Options[3] = "D) Hiçbiri geri dönmez"
+				return q
+			},
+			wantScore: 100,
+		},
+		{
+			name: "score does not go below zero",
+			modify: func(q []models.QuizQuestion) []models.QuizQuestion {
+				for i := 0; i < 5; i++ {
+					q = append(q, q[4])
+				}
+				return q
+			},
+			wantIssues: []string{
+				"duplicate_question:6", "duplicate_question:7", "duplicate_question:8",
+				"duplicate_question:9", "duplicate_question:10",
+			},
+			wantScore: 0,
+		},
+	}
+	for _, tt := range tests {
+		report := CheckQuizQuality(tt.modify(testQuizQuestions()))
+
+		var issues []string
+		for _, issue := range report.Issues {
+			issues = append(issues, fmt.Sprintf("%s:%d", issue.Check, issue.Question))
+		}
+		if !slices.Equal(issues, tt.wantIssues) {
+			t.Errorf("%s: issues = %q, want %q", tt.name, issues, tt.wantIssues)
+		}
+		if report.Score != tt.wantScore {
+			t.Errorf("%s: score = %d, want %d (%q)", tt.name, report.Score, tt.wantScore, report.Lines())
+		}
+	}
+}
+
+func TestTextSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		wantMin float64
+		wantMax float64
+	}{
+		{"Paris", "paris!", 1, 1},
+		{"Hikaye hangi şehirde başlar?", "Hikaye hangi şehirde başlıyor?", 0.85, 1},
+		{"Ahmet", "Fatma", 0, 0.3},
+		{"a", "b", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := textSimilarity(tt.a, tt.b); got < tt.wantMin || got > tt.wantMax {
+			t.Errorf("textSimilarity(%q, %q) = %v, want between %v and %v", tt.a, tt.b, got, tt.wantMin, tt.wantMax)
+		}
+	}
+}
@@ -0,0 +1,732 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// JSONSchema is the subset of JSON Schema used to describe quiz output. The
+// same schema is sent to the LLM as its response schema and used to
+// validate the response afterwards.
+type JSONSchema struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	MinItems    int                    `json:"minItems,omitempty"`
+	MaxItems    int                    `json:"maxItems,omitempty"`
+	MinLength   int                    `json:"minLength,omitempty"`
+	UniqueItems bool                   `json:"uniqueItems,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+}
+
+// quizOptionsCount is the number of options of a multiple choice question
+const quizOptionsCount = 4
+
+// Size limits of the other question types
+const (
+	multiSelectMinOptions  = 4
+	multiSelectMaxOptions  = 6
+	orderingMinItems       = 3
+	orderingMaxItems       = 6
+	shortAnswerMaxKeywords = 5
+)
+
+// quizOptionLetters are the option prefixes, in order
+var quizOptionLetters = []string{"A", "B", "C", "D", "E", "F"}
+
+// fillBlankMarker marks the blank in a fill_blank question
+const fillBlankMarker = "___"
+
+// NewQuizSchema returns the schema of a quiz response with count questions
+// (0 for any number) of the given types. Fields only some types use are
+// optional here and checked per type by checkQuestions.
+func NewQuizSchema(types []string, count int) *JSONSchema {
+	minItems := 1
+	if count > 0 {
+		minItems = count
+	}
+
+	text := func(description string) *JSONSchema {
+		return &JSONSchema{Type: "string", Description: description, MinLength: 1}
+	}
+	list := func(description string) *JSONSchema {
+		return &JSONSchema{Type: "array", Description: description, Items: text(""), UniqueItems: true}
+	}
+
+	return &JSONSchema{
+		Type:     "object",
+		Required: []string{"quiz"},
+		Properties: map[string]*JSONSchema{
+			"quiz": {
+				Type:     "array",
+				MinItems: minItems,
+				MaxItems: count,
+				Items: &JSONSchema{
+					Type:     "object",
+					Required: []string{"type", "question", "explanation"},
+					Properties: map[string]*JSONSchema{
+						"type":        {Type: "string", Description: "Soru tipi", Enum: types},
+						"question":    text("Soru metni"),
+						"options":     list(`Seçenekler; çoktan seçmelide "A) ...", "B) ..." biçiminde, sıralamada karışık sırada öğeler`),
+						"answer":      text("Doğru cevap: seçeneklerden biri, \"true\"/\"false\", boşluğa gelen ifade ya da örnek cevap"),
+						"answers":     list("Doğru seçeneklerin tamamı ya da öğelerin doğru sırası"),
+						"rubric":      text("Açık uçlu soru için değerlendirme ölçütü"),
+						"keywords":    list("Açık uçlu soruda doğru cevapta geçmesi gereken anahtar kelimeler"),
+						"explanation": text("Kısa açıklama"),
+					},
+				},
+			},
+		},
+	}
+}
+
+// SchemaViolation describes a value that does not match the schema.
+// Path uses JSON-path-like notation, e.g. "quiz[2].options".
+type SchemaViolation struct {
+	Path    string
+	Message string
+}
+
+// Validate checks value (as decoded by encoding/json into interface{})
+// against the schema and returns every violation found
+func (s *JSONSchema) Validate(value interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	s.validate(value, "", &violations)
+	return violations
+}
+
+func (s *JSONSchema) validate(value interface{}, path string, violations *[]SchemaViolation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				*violations = append(*violations, SchemaViolation{Path: joinPath(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := object[name]; ok {
+				s.Properties[name].validate(field, joinPath(path, name), violations)
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems > 0 && s.MinItems == s.MaxItems && len(items) != s.MinItems {
+			fail("must contain exactly %d items, got %d", s.MinItems, len(items))
+		} else if s.MinItems > 0 && len(items) < s.MinItems {
+			fail("must contain at least %d items, got %d", s.MinItems, len(items))
+		} else if s.MaxItems > 0 && len(items) > s.MaxItems {
+			fail("must contain at most %d items, got %d", s.MaxItems, len(items))
+		}
+		if s.UniqueItems {
+			seen := make(map[string]int)
+			for i, item := range items {
+				key, _ := json.Marshal(item)
+				if first, ok := seen[string(key)]; ok {
+					fail("items %d and %d are identical", first+1, i+1)
+					continue
+				}
+				seen[string(key)] = i
+			}
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if s.MinLength > 0 && utf8.RuneCountInString(strings.TrimSpace(text)) < s.MinLength {
+			fail("must not be empty")
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, text) {
+			fail("must be one of: %s", strings.Join(s.Enum, ", "))
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// toGenai converts the schema to a Gemini response schema. Gemini does not
+// support size constraints, so those are only enforced by Validate.
+func (s *JSONSchema) toGenai() *genai.Schema {
+	if s == nil {
+		return nil
+	}
+
+	schema := &genai.Schema{
+		Description: s.Description,
+		Required:    s.Required,
+		Items:       s.Items.toGenai(),
+		Enum:        s.Enum,
+	}
+	if len(s.Enum) > 0 {
+		schema.Format = "enum"
+	}
+	switch s.Type {
+	case "object":
+		schema.Type = genai.TypeObject
+	case "array":
+		schema.Type = genai.TypeArray
+	case "string":
+		schema.Type = genai.TypeString
+	}
+	if len(s.Properties) > 0 {
+		schema.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			schema.Properties[name] = property.toGenai()
+		}
+	}
+	return schema
+}
+
+// QuizIssue is a single problem found in a generated quiz. Question is the
+// 1-based question number, or 0 for problems with the quiz as a whole.
+type QuizIssue struct {
+	Question int
+	Field    string
+	Message  string
+}
+
+// String formats the issue, e.g. "question 2: answer: ..."
+func (i QuizIssue) String() string {
+	var parts []string
+	if i.Question > 0 {
+		parts = append(parts, fmt.Sprintf("question %d", i.Question))
+	}
+	if i.Field != "" {
+		parts = append(parts, i.Field)
+	}
+	parts = append(parts, i.Message)
+	return strings.Join(parts, ": ")
+}
+
+// QuizValidationError reports every issue found in a generated quiz
+type QuizValidationError struct {
+	Issues []QuizIssue
+}
+
+// Error implements the error interface
+func (e *QuizValidationError) Error() string {
+	return fmt.Sprintf("invalid quiz: %s", strings.Join(e.Lines(), "; "))
+}
+
+// Lines returns one message per issue
+func (e *QuizValidationError) Lines() []string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return lines
+}
+
+// ParseQuiz decodes an LLM response, repairs fixable issues and validates
+// the result against schema. It accepts both {"quiz": [...]} and a bare
+// array. The returned int is the number of repairs applied.
+func ParseQuiz(schema *JSONSchema, content []byte) (*models.QuizData, int, error) {
+	var value interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse quiz JSON: %w. Content: %s", err, content)
+	}
+	if questions, ok := value.([]interface{}); ok {
+		value = map[string]interface{}{"quiz": questions}
+	}
+
+	repairs := repairQuiz(value)
+
+	if issues := validateQuiz(schema, value); len(issues) > 0 {
+		return nil, repairs, &QuizValidationError{Issues: issues}
+	}
+
+	// The value matches the schema, so it decodes cleanly
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, repairs, fmt.Errorf("failed to marshal quiz: %w", err)
+	}
+	var quizData models.QuizData
+	if err := json.Unmarshal(normalized, &quizData); err != nil {
+		return nil, repairs, fmt.Errorf("failed to decode quiz: %w", err)
+	}
+	return &quizData, repairs, nil
+}
+
+// validateQuiz returns schema violations and rule violations of a decoded
+// quiz, ordered by question
+func validateQuiz(schema *JSONSchema, value interface{}) []QuizIssue {
+	var issues []QuizIssue
+	for _, violation := range schema.Validate(value) {
+		issues = append(issues, violationIssue(violation))
+	}
+	issues = append(issues, checkQuestions(value)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Question < issues[j].Question
+	})
+	return issues
+}
+
+// violationIssue maps a schema violation path like "quiz[2].options[1]" to
+// question 3, field "options[1]"
+func violationIssue(violation SchemaViolation) QuizIssue {
+	var index int
+	var rest string
+	if n, _ := fmt.Sscanf(violation.Path, "quiz[%d]%s", &index, &rest); n >= 1 {
+		return QuizIssue{
+			Question: index + 1,
+			Field:    strings.TrimPrefix(rest, "."),
+			Message:  violation.Message,
+		}
+	}
+	return QuizIssue{Field: violation.Path, Message: violation.Message}
+}
+
+// quizQuestions returns the question objects of a decoded quiz, skipping
+// anything with the wrong shape (reported by schema validation instead)
+func quizQuestions(value interface{}) map[int]map[string]interface{} {
+	questions := make(map[int]map[string]interface{})
+	object, _ := value.(map[string]interface{})
+	items, _ := object["quiz"].([]interface{})
+	for i, item := range items {
+		if question, ok := item.(map[string]interface{}); ok {
+			questions[i] = question
+		}
+	}
+	return questions
+}
+
+// checkQuestions reports rules JSON Schema cannot express: the fields each
+// question type needs, options that only differ in their prefix or case,
+// and answers that are not among the question's options
+func checkQuestions(value interface{}) []QuizIssue {
+	questions := quizQuestions(value)
+	indexes := make([]int, 0, len(questions))
+	for i := range questions {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var issues []QuizIssue
+	for _, i := range indexes {
+		for _, issue := range checkQuestion(questions[i]) {
+			issue.Question = i + 1
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// checkQuestion reports the type-specific issues of a single question
+func checkQuestion(question map[string]interface{}) []QuizIssue {
+	var issues []QuizIssue
+	fail := func(field, format string, args ...interface{}) {
+		issues = append(issues, QuizIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	require := func(fields ...string) bool {
+		ok := true
+		for _, field := range fields {
+			if _, present := question[field]; !present {
+				fail(field, "is required for %s questions", question["type"])
+				ok = false
+			}
+		}
+		return ok
+	}
+	count := func(field string, min, max int) {
+		if n := len(stringItems(question[field])); n < min || n > max {
+			if min == max {
+				fail(field, "must contain exactly %d items, got %d", min, n)
+			} else {
+				fail(field, "must contain %d to %d items, got %d", min, max, n)
+			}
+		}
+	}
+
+	options := stringItems(question["options"])
+	answer, _ := question["answer"].(string)
+	answers := stringItems(question["answers"])
+
+	switch question["type"] {
+	case models.QuestionMultipleChoice:
+		if !require("options", "answer") {
+			return issues
+		}
+		count("options", quizOptionsCount, quizOptionsCount)
+		issues = append(issues, checkOptionTexts(options)...)
+		if answer != "" && len(options) > 0 && optionIndex(options, answer) < 0 {
+			fail("answer", "%q is not one of the options", answer)
+		}
+
+	case models.QuestionTrueFalse:
+		if !require("answer") {
+			return issues
+		}
+		if answer != "" && answer != "true" && answer != "false" {
+			fail("answer", `must be "true" or "false", got %q`, answer)
+		}
+
+	case models.QuestionMultiSelect:
+		if !require("options", "answers") {
+			return issues
+		}
+		count("options", multiSelectMinOptions, multiSelectMaxOptions)
+		issues = append(issues, checkOptionTexts(options)...)
+		if len(answers) == 0 {
+			fail("answers", "must contain at least one correct option")
+		} else if len(answers) >= len(options) && len(options) > 0 {
+			fail("answers", "at least one option must be wrong")
+		}
+		for _, correct := range answers {
+			if len(options) > 0 && optionIndex(options, correct) < 0 {
+				fail("answers", "%q is not one of the options", correct)
+			}
+		}
+
+	case models.QuestionOrdering:
+		if !require("options", "answers") {
+			return issues
+		}
+		count("options", orderingMinItems, orderingMaxItems)
+		if !sameItems(options, answers) {
+			fail("answers", "must list exactly the options, in the correct order")
+		} else if slices.Equal(options, answers) {
+			fail("options", "must not already be in the correct order")
+		}
+
+	case models.QuestionFillBlank:
+		if !require("answer") {
+			return issues
+		}
+		if text, _ := question["question"].(string); text != "" && !strings.Contains(text, fillBlankMarker) {
+			fail("question", "must mark the blank with %q", fillBlankMarker)
+		}
+
+	case models.QuestionShortAnswer:
+		if !require("answer", "rubric", "keywords") {
+			return issues
+		}
+		count("keywords", 1, shortAnswerMaxKeywords)
+	}
+	return issues
+}
+
+// checkOptionTexts reports options that only differ in their prefix or case
+func checkOptionTexts(options []string) []QuizIssue {
+	var issues []QuizIssue
+	seen := make(map[string]string)
+	for _, option := range options {
+		text := option
+		if _, rest, ok := splitOption(option); ok {
+			text = rest
+		}
+		key := strings.ToLower(text)
+		if first, ok := seen[key]; ok && first != option {
+			issues = append(issues, QuizIssue{
+				Field:   "options",
+				Message: fmt.Sprintf("%q and %q are the same option", first, option),
+			})
+		}
+		seen[key] = option
+	}
+	return issues
+}
+
+// sameItems reports whether a and b hold the same items in any order
+func sameItems(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
+
+// repairQuiz fixes issues that don't need a new LLM call, in place:
+// surrounding whitespace, missing question types that are obvious from
+// the fields, options missing their "A) " prefixes, answers given as a
+// bare letter ("B", "b)") or as option text without the prefix, true/false
+// answers given as booleans or words, and ordering questions whose options
+// are already in order. It returns the number of repairs applied.
+func repairQuiz(value interface{}) int {
+	repairs := 0
+
+	for _, question := range quizQuestions(value) {
+		for _, field := range []string{"type", "question", "answer", "rubric", "explanation"} {
+			if text, ok := question[field].(string); ok && strings.TrimSpace(text) != text {
+				question[field] = strings.TrimSpace(text)
+				repairs++
+			}
+		}
+		for _, field := range []string{"options", "answers", "keywords"} {
+			repairs += trimItems(question[field])
+		}
+
+		if _, ok := question["type"]; !ok {
+			if questionType := inferQuestionType(question); questionType != "" {
+				question["type"] = questionType
+				repairs++
+			}
+		}
+
+		switch question["type"] {
+		case models.QuestionMultipleChoice:
+			repairs += repairOptionPrefixes(question, quizOptionsCount, quizOptionsCount)
+			repairs += repairChoiceAnswers(question, "answer")
+		case models.QuestionMultiSelect:
+			repairs += repairOptionPrefixes(question, multiSelectMinOptions, multiSelectMaxOptions)
+			repairs += repairChoiceAnswers(question, "answers")
+		case models.QuestionTrueFalse:
+			answer := question["answer"]
+			if flag, ok := answer.(bool); ok {
+				question["answer"] = strconv.FormatBool(flag)
+				repairs++
+			} else if text, ok := answer.(string); ok && text != "true" && text != "false" {
+				if flag, ok := parseTrueFalse(text); ok {
+					question["answer"] = strconv.FormatBool(flag)
+					repairs++
+				}
+			}
+		case models.QuestionOrdering:
+			repairs += repairOrdering(question)
+		}
+	}
+
+	return repairs
+}
+
+// trimItems trims the string items of a decoded JSON array in place
+func trimItems(value interface{}) int {
+	items, _ := value.([]interface{})
+	repairs := 0
+	for j, item := range items {
+		if text, ok := item.(string); ok && strings.TrimSpace(text) != text {
+			items[j] = strings.TrimSpace(text)
+			repairs++
+		}
+	}
+	return repairs
+}
+
+// inferQuestionType guesses the type of a question without one: options
+// with a single answer are multiple choice, a boolean answer is true/false
+func inferQuestionType(question map[string]interface{}) string {
+	_, hasOptions := question["options"]
+	_, hasAnswers := question["answers"]
+	switch answer := question["answer"].(type) {
+	case bool:
+		return models.QuestionTrueFalse
+	case string:
+		if hasOptions && !hasAnswers {
+			return models.QuestionMultipleChoice
+		}
+		if !hasOptions {
+			if _, ok := parseTrueFalse(answer); ok {
+				return models.QuestionTrueFalse
+			}
+		}
+	}
+	return ""
+}
+
+// repairOptionPrefixes adds missing "A) " prefixes when no option has one
+func repairOptionPrefixes(question map[string]interface{}, min, max int) int {
+	items, ok := question["options"].([]interface{})
+	if !ok {
+		return 0
+	}
+	options := stringItems(items)
+	if len(options) != len(items) || len(options) < min || len(options) > max {
+		return 0
+	}
+	for _, option := range options {
+		if _, _, ok := splitOption(option); ok {
+			return 0
+		}
+	}
+	for j := range items {
+		items[j] = quizOptionLetters[j] + ") " + options[j]
+	}
+	return 1
+}
+
+// repairChoiceAnswers replaces abbreviated answers in field (a string or
+// a list) with the options they refer to
+func repairChoiceAnswers(question map[string]interface{}, field string) int {
+	options := stringItems(question["options"])
+	if len(options) == 0 {
+		return 0
+	}
+	repair := func(answer string) (string, bool) {
+		if answer == "" || optionIndex(options, answer) >= 0 {
+			return answer, false
+		}
+		if j := matchAnswer(options, answer); j >= 0 {
+			return options[j], true
+		}
+		return answer, false
+	}
+
+	repairs := 0
+	switch answer := question[field].(type) {
+	case string:
+		if repaired, ok := repair(answer); ok {
+			question[field] = repaired
+			repairs++
+		}
+	case []interface{}:
+		for j, item := range answer {
+			if text, ok := item.(string); ok {
+				if repaired, ok := repair(text); ok {
+					answer[j] = repaired
+					repairs++
+				}
+			}
+		}
+	}
+	return repairs
+}
+
+// repairOrdering makes the answers of an ordering question use the exact
+// option texts and, when the options are already in the correct order,
+// rotates them so the question isn't given away
+func repairOrdering(question map[string]interface{}) int {
+	options := stringItems(question["options"])
+	items, ok := question["answers"].([]interface{})
+	if !ok || len(options) == 0 {
+		return 0
+	}
+
+	repairs := 0
+	for j, item := range items {
+		text, ok := item.(string)
+		if !ok || optionIndex(options, text) >= 0 {
+			continue
+		}
+		for _, option := range options {
+			if strings.EqualFold(option, text) {
+				items[j] = option
+				repairs++
+				break
+			}
+		}
+	}
+
+	if answers := stringItems(items); len(options) > 1 && slices.Equal(options, answers) {
+		rotated := make([]interface{}, len(options))
+		for j := range options {
+			rotated[j] = options[(j+1)%len(options)]
+		}
+		question["options"] = rotated
+		repairs++
+	}
+	return repairs
+}
+
+// parseTrueFalse reads a true/false answer given as a word
+func parseTrueFalse(text string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "true", "doğru", "dogru", "evet", "yes":
+		return true, true
+	case "false", "yanlış", "yanlis", "hayır", "hayir", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// matchAnswer finds the option an abbreviated answer refers to
+func matchAnswer(options []string, answer string) int {
+	// Bare letter: "B", "b", "B)", "B."
+	letter := strings.ToUpper(strings.TrimRight(answer, ").: "))
+	if utf8.RuneCountInString(letter) == 1 {
+		for j, option := range options {
+			if optionLetter, _, ok := splitOption(option); ok && optionLetter == letter {
+				return j
+			}
+		}
+	}
+
+	// Option text without (or with a differently formatted) prefix
+	text := answer
+	if _, rest, ok := splitOption(answer); ok {
+		text = rest
+	}
+	for j, option := range options {
+		if _, rest, ok := splitOption(option); ok && strings.EqualFold(rest, text) {
+			return j
+		}
+	}
+	return -1
+}
+
+// splitOption splits "B) text" (or "B. text", "B: text") into "B" and "text"
+func splitOption(option string) (string, string, bool) {
+	if len(option) < 2 {
+		return "", "", false
+	}
+	letter := strings.ToUpper(option[:1])
+	if !slices.Contains(quizOptionLetters, letter) || !strings.ContainsRune(").:", rune(option[1])) {
+		return "", "", false
+	}
+	return letter, strings.TrimSpace(option[2:]), true
+}
+
+// optionIndex returns the index of the option equal to answer, or -1
+func optionIndex(options []string, answer string) int {
+	for j, option := range options {
+		if option == answer {
+			return j
+		}
+	}
+	return -1
+}
+
+// stringItems returns the string elements of a decoded JSON array
+func stringItems(value interface{}) []string {
+	items, _ := value.([]interface{})
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		if text, ok := item.(string); ok {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+func TestParseQuizRepairs(t *testing.T) {
+	schema := NewQuizSchema(models.QuestionTypes, 0)
+
+	tests := []struct {
+		name        string
+		content     string
+		want        models.QuizQuestion
+		wantRepairs int
+	}{
+		{
+			name:    "valid",
+			content: `{"quiz": [{"type": "multiple_choice", "question": "Başkent?", "options": ["A) Paris", "B) Roma", "C) Oslo", "D) Bern"], "answer": "B) Roma", "explanation": "Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionMultipleChoice, Question: "Başkent?",
+				Options: []string{"A) Paris", "B) Roma", "C) Oslo", "D) Bern"}, Answer: "B) Roma", Explanation: "Roma"},
+		},
+		{
+			name:    "bare array",
+			content: `[{"type": "true_false", "question": "Roma başkenttir.", "answer": "true", "explanation": "Evet"}]`,
+			want:    models.QuizQuestion{Type: models.QuestionTrueFalse, Question: "Roma başkenttir.", Answer: "true", Explanation: "Evet"},
+		},
+		{
+			name:    "whitespace",
+			content: `{"quiz": [{"type": "fill_blank ", "question": " Başkent ___ ", "answer": "Roma", "answers": [" Rome"], "explanation": "Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionFillBlank, Question: "Başkent ___", Answer: "Roma",
+				Answers: []string{"Rome"}, Explanation: "Roma"},
+			wantRepairs: 3,
+		},
+		{
+			name:    "option prefixes and answer text",
+			content: `{"quiz": [{"type": "multiple_choice", "question": "Başkent?", "options": ["Paris", "Roma", "Oslo", "Bern"], "answer": "roma", "explanation": "Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionMultipleChoice, Question: "Başkent?",
+				Options: []string{"A) Paris", "B) Roma", "C) Oslo", "D) Bern"}, Answer: "B) Roma", Explanation: "Roma"},
+			wantRepairs: 2,
+		},
+		{
+			name:    "answer letter",
+			content: `{"quiz": [{"type": "multiple_choice", "question": "Başkent?", "options": ["A) Paris", "B) Roma", "C) Oslo", "D) Bern"], "answer": "b)", "explanation": "Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionMultipleChoice, Question: "Başkent?",
+				Options: []string{"A) Paris", "B) Roma", "C) Oslo", "D) Bern"}, Answer: "B) Roma", Explanation: "Roma"},
+			wantRepairs: 1,
+		},
+		{
+			name:    "multi select letters",
+			content: `{"quiz": [{"type": "multi_select", "question": "Başkentler?", "options": ["A) Paris", "B) Lyon", "C) Roma", "D) Milano"], "answers": ["A", "c"], "explanation": "Paris ve Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionMultiSelect, Question: "Başkentler?",
+				Options: []string{"A) Paris", "B) Lyon", "C) Roma", "D) Milano"}, Answers: []string{"A) Paris", "C) Roma"}, Explanation: "Paris ve Roma"},
+			wantRepairs: 2,
+		},
+		{
+			name:    "boolean answer",
+			content: `{"quiz": [{"type": "true_false", "question": "Roma başkenttir.", "answer": true, "explanation": "Evet"}]}`,
+			want:    models.QuizQuestion{Type: models.QuestionTrueFalse, Question: "Roma başkenttir.", Answer: "true", Explanation: "Evet"},
+			// The answer is repaired before validation, so the schema accepts it
+			wantRepairs: 1,
+		},
+		{
+			name:        "true/false word",
+			content:     `{"quiz": [{"type": "true_false", "question": "Oslo başkent değildir.", "answer": "Yanlış", "explanation": "Başkenttir"}]}`,
+			want:        models.QuizQuestion{Type: models.QuestionTrueFalse, Question: "Oslo başkent değildir.", Answer: "false", Explanation: "Başkenttir"},
+			wantRepairs: 1,
+		},
+		{
+			name:    "missing type",
+			content: `{"quiz": [{"question": "Başkent?", "options": ["A) Paris", "B) Roma", "C) Oslo", "D) Bern"], "answer": "B) Roma", "explanation": "Roma"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionMultipleChoice, Question: "Başkent?",
+				Options: []string{"A) Paris", "B) Roma", "C) Oslo", "D) Bern"}, Answer: "B) Roma", Explanation: "Roma"},
+			wantRepairs: 1,
+		},
+		{
+			name:    "ordering given in order",
+			content: `{"quiz": [{"type": "ordering", "question": "Sırala", "options": ["Bir", "İki", "Üç"], "answers": ["bir", "İki", "Üç"], "explanation": "Sayılar"}]}`,
+			want: models.QuizQuestion{Type: models.QuestionOrdering, Question: "Sırala",
+				Options: []string{"İki", "Üç", "Bir"}, Answers: []string{"Bir", "İki", "Üç"}, Explanation: "Sayılar"},
+			wantRepairs: 2,
+		},
+	}
+	for _, tt := range tests {
+		quiz, repairs, err := ParseQuiz(schema, []byte(tt.content))
+		if err != nil {
+			t.Errorf("%s: ParseQuiz() unexpected error: %v", tt.name, err)
+			continue
+		}
+		if repairs != tt.wantRepairs {
+			t.Errorf("%s: repairs = %d, want %d", tt.name, repairs, tt.wantRepairs)
+		}
+		if len(quiz.Quiz) != 1 || !reflect.DeepEqual(quiz.Quiz[0], tt.want) {
+			t.Errorf("%s: ParseQuiz() = %+v, want %+v", tt.name, quiz.Quiz, tt.want)
+		}
+	}
+}
+
+func TestParseQuizInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		count      int
+		content    string
+		wantIssues []string // Empty for errors other than QuizValidationError
+	}{
+		{
+			name:    "not JSON",
+			content: `{"quiz": [`,
+		},
+		{
+			name:       "wrong question count",
+			count:      2,
+			content:    `{"quiz": [{"type": "true_false", "question": "Roma başkenttir.", "answer": "true", "explanation": "Evet"}]}`,
+			wantIssues: []string{"quiz: must contain exactly 2 items, got 1"},
+		},
+		{
+			name:       "missing quiz",
+			content:    `{"questions": []}`,
+			wantIssues: []string{"quiz: is required"},
+		},
+		{
+			name:       "answer not an option",
+			content:    `{"quiz": [{"type": "multiple_choice", "question": "Başkent?", "options": ["A) Paris", "B) Roma", "C) Oslo", "D) Bern"], "answer": "Londra", "explanation": "Roma"}]}`,
+			wantIssues: []string{`question 1: answer: "Londra" is not one of the options`},
+		},
+		{
+			name:       "options differing in case",
+			content:    `{"quiz": [{"type": "multiple_choice", "question": "Başkent?", "options": ["A) Paris", "B) Roma", "C) roma", "D) Bern"], "answer": "B) Roma", "explanation": "Roma"}]}`,
+			wantIssues: []string{`question 1: options: "B) Roma" and "C) roma" are the same option`},
+		},
+		{
+			name:       "every option correct",
+			content:    `{"quiz": [{"type": "multi_select", "question": "Başkentler?", "options": ["A) Paris", "B) Roma", "C) Oslo", "D) Bern"], "answers": ["A", "B", "C", "D"], "explanation": "Hepsi"}]}`,
+			wantIssues: []string{"question 1: answers: at least one option must be wrong"},
+		},
+		{
+			name:       "fill blank without marker",
+			content:    `{"quiz": [{"type": "fill_blank", "question": "Başkent nedir?", "answer": "Roma", "explanation": "Roma"}]}`,
+			wantIssues: []string{`question 1: question: must mark the blank with "___"`},
+		},
+		{
+			name:       "short answer without rubric",
+			content:    `[{"type": "true_false", "question": "Roma başkenttir.", "answer": "true", "explanation": "Evet"}, {"type": "short_answer", "question": "Neden?", "answer": "Çünkü", "keywords": ["tarih"], "explanation": "Tarih"}]`,
+			wantIssues: []string{"question 2: rubric: is required for short_answer questions"},
+		},
+		{
+			name:    "unknown type",
+			content: `[{"type": "essay", "question": "Yaz", "answer": "Metin", "explanation": "Serbest"}]`,
+			wantIssues: []string{"question 1: type: must be one of: " +
+				"multiple_choice, true_false, multi_select, ordering, fill_blank, short_answer"},
+		},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseQuiz(NewQuizSchema(models.QuestionTypes, tt.count), []byte(tt.content))
+		if err == nil {
+			t.Errorf("%s: ParseQuiz() want error", tt.name)
+			continue
+		}
+
+		var validationErr *QuizValidationError
+		if !errors.As(err, &validationErr) {
+			if len(tt.wantIssues) > 0 {
+				t.Errorf("%s: ParseQuiz() error = %v, want QuizValidationError", tt.name, err)
+			}
+			continue
+		}
+		if !slices.Equal(validationErr.Lines(), tt.wantIssues) {
+			t.Errorf("%s: issues = %q, want %q", tt.name, validationErr.Lines(), tt.wantIssues)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+// QuestionMix is the share of each question type in generated quizzes
+type QuestionMix struct {
+	types   []string // In models.QuestionTypes order
+	weights map[string]int
+}
+
+// NewQuestionMix creates a mix from type weights (QUIZ_QUESTION_TYPES)
+func NewQuestionMix(weights map[string]int) (*QuestionMix, error) {
+	mix := &QuestionMix{weights: map[string]int{}}
+	for name, weight := range weights {
+		if !slices.Contains(models.QuestionTypes, name) {
+			return nil, fmt.Errorf("unknown question type %q in QUIZ_QUESTION_TYPES (expected one of: %s)", name, strings.Join(models.QuestionTypes, ", "))
+		}
+		if weight > 0 {
+			mix.weights[name] = weight
+		}
+	}
+	for _, name := range models.QuestionTypes {
+		if mix.weights[name] > 0 {
+			mix.types = append(mix.types, name)
+		}
+	}
+	if len(mix.types) == 0 {
+		return nil, fmt.Errorf("QUIZ_QUESTION_TYPES selects no question type")
+	}
+	return mix, nil
+}
+
+// Types returns the question types of the mix
+func (m *QuestionMix) Types() []string {
+	return m.types
+}
+
+// QuestionTypeCount is the number of questions of one type in a quiz
+type QuestionTypeCount struct {
+	Type  string
+	Count int
+}
+
+// Counts splits total questions between the types by weight (largest
+// remainder), leaving out types that get no question
+func (m *QuestionMix) Counts(total int) []QuestionTypeCount {
+	sum := 0
+	for _, name := range m.types {
+		sum += m.weights[name]
+	}
+
+	counts := make([]QuestionTypeCount, len(m.types))
+	remainders := make([]int, len(m.types))
+	assigned := 0
+	for i, name := range m.types {
+		share := total * m.weights[name]
+		counts[i] = QuestionTypeCount{Type: name, Count: share / sum}
+		remainders[i] = share % sum
+		assigned += counts[i].Count
+	}
+
+	order := make([]int, len(m.types))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for _, i := range order[:total-assigned] {
+		counts[i].Count++
+	}
+
+	result := counts[:0]
+	for _, count := range counts {
+		if count.Count > 0 {
+			result = append(result, count)
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bookwise/api/internal/models"
+)
+
+func TestNewQuestionMix(t *testing.T) {
+	tests := []struct {
+		name      string
+		weights   map[string]int
+		wantTypes []string
+		wantErr   bool
+	}{
+		{"single type", map[string]int{models.QuestionMultipleChoice: 1}, []string{models.QuestionMultipleChoice}, false},
+		{"models order", map[string]int{models.QuestionShortAnswer: 1, models.QuestionTrueFalse: 2, models.QuestionMultipleChoice: 3},
+			[]string{models.QuestionMultipleChoice, models.QuestionTrueFalse, models.QuestionShortAnswer}, false},
+		{"zero weight left out", map[string]int{models.QuestionMultipleChoice: 1, models.QuestionOrdering: 0}, []string{models.QuestionMultipleChoice}, false},
+		{"unknown type", map[string]int{"essay": 1}, nil, true},
+		{"no weight", map[string]int{models.QuestionMultipleChoice: 0}, nil, true},
+		{"empty", map[string]int{}, nil, true},
+	}
+	for _, tt := range tests {
+		mix, err := NewQuestionMix(tt.weights)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: NewQuestionMix() = %v, want error", tt.name, mix.Types())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewQuestionMix() unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(mix.Types(), tt.wantTypes) {
+			t.Errorf("%s: Types() = %q, want %q", tt.name, mix.Types(), tt.wantTypes)
+		}
+	}
+}
+
+func TestQuestionMixCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		total   int
+		want    []QuestionTypeCount
+	}{
+		{"single type", map[string]int{models.QuestionMultipleChoice: 1}, 5,
+			[]QuestionTypeCount{{models.QuestionMultipleChoice, 5}}},
+		{"even split", map[string]int{models.QuestionMultipleChoice: 1, models.QuestionTrueFalse: 1}, 4,
+			[]QuestionTypeCount{{models.QuestionMultipleChoice, 2}, {models.QuestionTrueFalse, 2}}},
+		{"largest remainder", map[string]int{models.QuestionMultipleChoice: 3, models.QuestionTrueFalse: 1, models.QuestionFillBlank: 1}, 7,
+			// 4.2, 1.4, 1.4: the spare question goes to the first of the tied remainders
+			[]QuestionTypeCount{{models.QuestionMultipleChoice, 4}, {models.QuestionTrueFalse, 2}, {models.QuestionFillBlank, 1}}},
+		{"remainder order", map[string]int{models.QuestionMultipleChoice: 1, models.QuestionTrueFalse: 2}, 2,
+			// 0.67 and 1.33: true_false keeps its whole question, multiple_choice gets the spare one
+			[]QuestionTypeCount{{models.QuestionMultipleChoice, 1}, {models.QuestionTrueFalse, 1}}},
+		{"types without a question left out", map[string]int{models.QuestionMultipleChoice: 8, models.QuestionTrueFalse: 1, models.QuestionOrdering: 1}, 3,
+			// 2.4, 0.3, 0.3
+			[]QuestionTypeCount{{models.QuestionMultipleChoice, 3}}},
+		{"every type", map[string]int{
+			models.QuestionMultipleChoice: 1, models.QuestionTrueFalse: 1, models.QuestionMultiSelect: 1,
+			models.QuestionOrdering: 1, models.QuestionFillBlank: 1, models.QuestionShortAnswer: 1,
+		}, 6, []QuestionTypeCount{
+			{models.QuestionMultipleChoice, 1}, {models.QuestionTrueFalse, 1}, {models.QuestionMultiSelect, 1},
+			{models.QuestionOrdering, 1}, {models.QuestionFillBlank, 1}, {models.QuestionShortAnswer, 1},
+		}},
+		{"no questions", map[string]int{models.QuestionMultipleChoice: 1}, 0, []QuestionTypeCount{}},
+	}
+	for _, tt := range tests {
+		mix, err := NewQuestionMix(tt.weights)
+		if err != nil {
+			t.Fatalf("%s: NewQuestionMix() error: %v", tt.name, err)
+		}
+		got := mix.Counts(tt.total)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Counts(%d) = %v, want %v", tt.name, tt.total, got, tt.want)
+		}
+
+		sum := 0
+		for _, count := range got {
+			sum += count.Count
+		}
+		if sum != tt.total {
+			t.Errorf("%s: Counts(%d) sums to %d", tt.name, tt.total, sum)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bookwise/api/internal/database"
+	"github.com/bookwise/api/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Quiz version errors
+var (
+	ErrQuizNotFound     = errors.New("quiz not found")
+	ErrQuizNotCompleted = errors.New("only completed quizzes can be activated")
+)
+
+// ListQuizVersions returns every quiz version of a book, newest first. A
+// non-empty difficulty or language only lists versions that have it.
+func ListQuizVersions(bookID uuid.UUID, difficulty, language string) ([]models.Quiz, error) {
+	var quizzes []models.Quiz
+	query := quizVariant(database.DB.Where("book_id = ? AND version > 0", bookID), difficulty, language)
+	err := query.Order("version DESC").Find(&quizzes).Error
+	return quizzes, err
+}
+
+// ActivateQuizVersion makes the given quiz version the book's active quiz.
+// Older versions stay stored, so activating one is a rollback.
+func ActivateQuizVersion(bookID, quizID uuid.UUID) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := database.DB.Where("id = ? AND book_id = ?", quizID, bookID).First(&quiz).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrQuizNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if quiz.Status != "completed" || quiz.Version == 0 {
+		return nil, ErrQuizNotCompleted
+	}
+
+	if err := database.DB.Model(&models.Book{}).Where("id = ?", bookID).Updates(map[string]interface{}{
+		"quiz_id":     quiz.ID,
+		"quiz_status": "completed",
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("⏪ Quiz version %d (%s) activated for book %s", quiz.Version, quiz.ID, bookID)
+	return &quiz, nil
+}
+
+// saveQuizVersion stores a completed quiz as the book's next version. It
+// becomes the active quiz unless the book's active quiz has another
+// difficulty or language, which stays the default.
+func saveQuizVersion(quiz *models.Quiz) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the book row so concurrent saves get distinct versions
+		var book models.Book
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "quiz_id").Where("id = ?", quiz.BookID).First(&book).Error; err != nil {
+			return fmt.Errorf("failed to lock book: %w", err)
+		}
+
+		var latest int
+		if err := tx.Model(&models.Quiz{}).
+			Where("book_id = ?", quiz.BookID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to determine quiz version: %w", err)
+		}
+		quiz.Version = latest + 1
+
+		if err := tx.Create(quiz).Error; err != nil {
+			return fmt.Errorf("failed to save quiz to database: %w", err)
+		}
+
+		if book.QuizID != nil {
+			var active models.Quiz
+			err := tx.Select("difficulty", "language").Where("id = ?", *book.QuizID).First(&active).Error
+			if err == nil && (active.Difficulty != quiz.Difficulty || active.Language != quiz.Language) {
+				return nil
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to load active quiz: %w", err)
+			}
+		}
+
+		return tx.Model(&models.Book{}).Where("id = ?", quiz.BookID).Updates(map[string]interface{}{
+			"quiz_id":     quiz.ID,
+			"quiz_status": "completed",
+		}).Error
+	})
+}
@@ -2,211 +2,835 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bookwise/api/config"
 	"github.com/bookwise/api/internal/database"
 	"github.com/bookwise/api/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// QuizWorker handles background quiz generation
+// Quiz job management errors
+var (
+	ErrJobNotFound       = errors.New("quiz job not found")
+	ErrJobNotCancellable = errors.New("quiz job is already finished")
+	ErrJobNotRetryable   = errors.New("only failed or cancelled quiz jobs can be retried")
+	ErrJobAlreadyActive  = errors.New("book already has an active quiz job for this difficulty and language")
+	ErrJobCancelled      = errors.New("quiz job cancelled")
+	ErrWorkerStopping    = errors.New("quiz worker pool is still stopping")
+)
+
+// QuizWorker handles background quiz generation from the persistent quiz_jobs queue
 type QuizWorker struct {
-	generator  *QuizGeneratorService
-	queue      chan uuid.UUID
-	wg         sync.WaitGroup
-	workerCount int
-	running    bool
-	mu         sync.Mutex
+	generator    *QuizGeneratorService
+	workerID     string
+	wg           sync.WaitGroup
+	workerCount  int
+	running      bool
+	mu           sync.Mutex
+	stop         chan struct{}
+	stopped      chan struct{} // Closed once the workers of the last Start have exited
+	wake         chan struct{}
+	maxAttempts  int
+	pollInterval time.Duration
+	lockTimeout  time.Duration
+	budgetPaused atomic.Bool
 }
 
 // NewQuizWorker creates a new quiz worker
-func NewQuizWorker(cfg *config.Config, workerCount int) *QuizWorker {
-	return &QuizWorker{
-		generator:   NewQuizGeneratorService(cfg),
-		queue:       make(chan uuid.UUID, 100),
-		workerCount: workerCount,
-		running:     false,
+func NewQuizWorker(cfg *config.Config, workerCount int) (*QuizWorker, error) {
+	generator, err := NewQuizGeneratorService(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quiz generator: %w", err)
 	}
+
+	hostname, _ := os.Hostname()
+
+	stopped := make(chan struct{})
+	close(stopped)
+
+	return &QuizWorker{
+		generator:    generator,
+		workerID:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		workerCount:  workerCount,
+		running:      false,
+		stopped:      stopped,
+		wake:         make(chan struct{}, workerCount),
+		maxAttempts:  cfg.Quiz.JobMaxAttempts,
+		pollInterval: cfg.Quiz.JobPollInterval,
+		lockTimeout:  cfg.Quiz.JobLockTimeout,
+	}, nil
 }
 
-// Start starts the worker pool
-func (w *QuizWorker) Start() {
+// Start starts the worker pool. It returns ErrWorkerStopping while the
+// workers of a previous Stop are still finishing their jobs.
+func (w *QuizWorker) Start() error {
 	w.mu.Lock()
 	if w.running {
 		w.mu.Unlock()
-		return
+		return nil
+	}
+	if w.stoppingLocked() {
+		w.mu.Unlock()
+		return ErrWorkerStopping
 	}
 	w.running = true
+	w.stop = make(chan struct{})
 	w.mu.Unlock()
 
-	log.Printf("🚀 Starting quiz worker pool with %d workers", w.workerCount)
+	log.Printf("🚀 Starting quiz worker pool with %d workers (id: %s)", w.workerCount, w.workerID)
+
+	// Requeue jobs left running by crashed replicas
+	w.recoverStaleJobs()
 
 	for i := 0; i < w.workerCount; i++ {
 		w.wg.Add(1)
 		go w.worker(i + 1)
 	}
+
+	w.wg.Add(1)
+	go w.staleJobMonitor()
+	return nil
 }
 
-// Stop stops the worker pool
+// Stop stops the worker pool and waits until it has stopped. Jobs already
+// claimed are finished first; queued jobs stay in the database for the
+// next start.
 func (w *QuizWorker) Stop() {
+	<-w.BeginStop()
+}
+
+// BeginStop tells the worker pool to stop without waiting for running jobs
+// and returns a channel closed once every worker has exited. The lock is
+// not held while jobs finish, so Running, GetStats and Start don't block
+// on in-flight LLM calls.
+func (w *QuizWorker) BeginStop() <-chan struct{} {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if !w.running {
-		return
+		return w.stopped
 	}
 
 	log.Println("🛑 Stopping quiz worker pool...")
-	close(w.queue)
-	w.wg.Wait()
 	w.running = false
-	log.Println("✅ Quiz worker pool stopped")
+	close(w.stop)
+
+	stopped := make(chan struct{})
+	w.stopped = stopped
+	go func() {
+		w.wg.Wait()
+		log.Println("✅ Quiz worker pool stopped")
+		close(stopped)
+	}()
+	return stopped
 }
 
-// Enqueue adds a book ID to the quiz generation queue
-func (w *QuizWorker) Enqueue(bookID uuid.UUID) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// stoppingLocked reports whether workers of a previous start are still
+// finishing their jobs. w.mu must be held.
+func (w *QuizWorker) stoppingLocked() bool {
+	select {
+	case <-w.stopped:
+		return false
+	default:
+		return true
+	}
+}
 
-	if !w.running {
-		log.Println("⚠️ Worker not running, cannot enqueue")
-		return
+// QuizJobOptions controls how a quiz generation job is run
+type QuizJobOptions struct {
+	// Force generates a new quiz version even if the book already has one
+	Force bool
+	// Params are the parameters of the quiz to generate; the zero value is
+	// a medium quiz with QUIZ_QUESTIONS_COUNT questions in the book's
+	// language (Turkish if quizzes can't be generated in it)
+	Params models.QuizParams
+}
+
+// Enqueue adds a quiz generation job for the book and returns it. If the
+// book already has an active (queued or running) job for the same
+// difficulty and language, that job is returned.
+func (w *QuizWorker) Enqueue(bookID uuid.UUID, opts QuizJobOptions) (*models.QuizJob, error) {
+	job, err := EnqueueQuizJob(bookID, w.maxAttempts, opts)
+	if err != nil {
+		return nil, err
 	}
 
+	// Wake an idle local worker instead of waiting for the next poll
 	select {
-	case w.queue <- bookID:
-		log.Printf("📝 Book %s added to quiz generation queue", bookID)
+	case w.wake <- struct{}{}:
 	default:
-		log.Printf("⚠️ Quiz queue is full, skipping book %s", bookID)
 	}
+
+	return job, nil
+}
+
+// EnqueueQuizJob persists a quiz generation job for the book. It does not
+// need a running worker, so it can be used from CLI tools as well.
+func EnqueueQuizJob(bookID uuid.UUID, maxAttempts int, opts QuizJobOptions) (*models.QuizJob, error) {
+	params, err := NormalizeQuizParams(opts.Params)
+	if err != nil {
+		return nil, err
+	}
+	if params.Language == "" {
+		var book models.Book
+		if err := database.DB.Select("id", "language").Where("id = ?", bookID).First(&book).Error; err != nil {
+			return nil, fmt.Errorf("failed to load book %s: %w", bookID, err)
+		}
+		params.Language = ResolveQuizLanguage(book.Language)
+	}
+
+	job := &models.QuizJob{
+		BookID:      bookID,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: maxAttempts,
+		RunAfter:    time.Now(),
+		QuizParams:  params,
+		Force:       opts.Force,
+	}
+
+	// The partial unique index allows a single active job per book, difficulty and language
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to enqueue quiz job: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		var existing models.QuizJob
+		if err := database.DB.Where("book_id = ? AND difficulty = ? AND language = ? AND status IN ?", bookID, params.Difficulty, params.Language, []string{models.JobStatusQueued, models.JobStatusRunning}).
+			First(&existing).Error; err != nil {
+			return nil, fmt.Errorf("failed to load active quiz job: %w", err)
+		}
+		log.Printf("ℹ️ Book %s already has an active %s/%s quiz job %s", bookID, params.Difficulty, params.Language, existing.ID)
+
+		// A queued job that hasn't started yet can still be upgraded to a forced regeneration
+		if opts.Force && !existing.Force && existing.Status == models.JobStatusQueued {
+			result := database.DB.Model(&existing).Where("status = ?", models.JobStatusQueued).Update("force", true)
+			if result.Error != nil {
+				return nil, fmt.Errorf("failed to upgrade quiz job %s to a forced regeneration: %w", existing.ID, result.Error)
+			}
+		}
+		return &existing, nil
+	}
+
+	log.Printf("📝 Book %s added to quiz generation queue (job: %s, difficulty: %s, language: %s)", bookID, job.ID, params.Difficulty, params.Language)
+	return job, nil
 }
 
-// worker processes quiz generation jobs
+// worker claims and processes quiz generation jobs until stopped
 func (w *QuizWorker) worker(id int) {
 	defer w.wg.Done()
 
 	log.Printf("👷 Worker #%d started", id)
 
-	for bookID := range w.queue {
-		log.Printf("👷 Worker #%d processing book %s", id, bookID)
-		w.processQuizGeneration(bookID)
+	for {
+		select {
+		case <-w.stop:
+			log.Printf("👷 Worker #%d stopped", id)
+			return
+		default:
+		}
+
+		// Don't claim jobs that can't make an LLM call today
+		if w.waitForBudget() {
+			select {
+			case <-w.stop:
+				log.Printf("👷 Worker #%d stopped", id)
+				return
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		job, err := w.claimJob()
+		if err != nil {
+			log.Printf("❌ Worker #%d failed to claim job: %v", id, err)
+		}
+		if job != nil {
+			log.Printf("👷 Worker #%d processing job %s for book %s (attempt %d/%d)", id, job.ID, job.BookID, job.Attempts, job.MaxAttempts)
+			w.runJob(job)
+			continue
+		}
+
+		select {
+		case <-w.stop:
+			log.Printf("👷 Worker #%d stopped", id)
+			return
+		case <-w.wake:
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+// waitForBudget reports whether generation is paused because the daily LLM
+// budget is exhausted, logging once when the pause starts and ends
+func (w *QuizWorker) waitForBudget() bool {
+	budget := w.generator.Budget()
+	if !budget.Limited() {
+		return false
+	}
+
+	exhausted := budget.Exhausted()
+	if exhausted && !w.budgetPaused.Swap(true) {
+		log.Printf("💸 Daily LLM budget exhausted; quiz generation paused until %s", NextBudgetReset(time.Now()).Format(time.RFC3339))
+	}
+	if !exhausted && w.budgetPaused.Swap(false) {
+		log.Println("💸 Daily LLM budget available again; quiz generation resumed")
+	}
+	return exhausted
+}
+
+// claimJob atomically claims the next due job, or returns nil if none is due
+func (w *QuizWorker) claimJob() (*models.QuizJob, error) {
+	var job models.QuizJob
+	now := time.Now()
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_after <= ?", models.JobStatusQueued, now).
+			Order("run_after, created_at").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":     models.JobStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"locked_by":  w.workerID,
+			"locked_at":  now,
+			"started_at": gorm.Expr("COALESCE(started_at, ?)", now),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.Status = models.JobStatusRunning
+	job.Attempts++
+	job.LockedBy = w.workerID
+	job.LockedAt = &now
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	return &job, nil
+}
+
+// runJob executes a claimed job and records its outcome
+func (w *QuizWorker) runJob(job *models.QuizJob) {
+	attempt := &models.QuizJobAttempt{
+		JobID:     job.ID,
+		Attempt:   job.Attempts,
+		WorkerID:  w.workerID,
+		Status:    models.AttemptStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := database.DB.Create(attempt).Error; err != nil {
+		log.Printf("❌ Failed to record attempt for quiz job %s: %v", job.ID, err)
+	}
+
+	// Keep the lock fresh so a long generation isn't requeued as stale
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		w.heartbeat(job.ID, done)
+	}()
+	err := w.processQuizGeneration(job)
+	close(done)
+	<-stopped
+
+	if err != nil && !errors.Is(err, ErrJobCancelled) && w.cancelRequested(job.ID) {
+		err = fmt.Errorf("%w: %v", ErrJobCancelled, err)
+	}
+	w.finishAttempt(attempt, err)
+
+	switch {
+	case err == nil:
+		w.finishJob(job, models.JobStatusCompleted, "", time.Time{})
+		return
+
+	case errors.Is(err, ErrJobCancelled):
+		log.Printf("🚫 Quiz job %s was cancelled while running", job.ID)
+		w.finishJob(job, models.JobStatusCancelled, err.Error(), time.Time{})
+		w.resetGeneratingBook(job.BookID)
+		return
+
+	case errors.Is(err, ErrLLMBudgetExhausted):
+		// Not the job's fault: requeue for the next budget day without
+		// spending one of its attempts
+		resetAt := NextBudgetReset(time.Now())
+		log.Printf("💸 Quiz job %s postponed until %s: daily LLM budget exhausted", job.ID, resetAt.Format(time.RFC3339))
+		w.finishJob(job, models.JobStatusQueued, err.Error(), resetAt)
+		database.DB.Model(job).Update("attempts", gorm.Expr("GREATEST(attempts - 1, 0)"))
+		setBookQuizStatus(job.BookID, "pending")
+		return
+	}
+
+	lastError := err.Error()
+	var genErr *GenerationError
+	if errors.As(err, &genErr) && genErr.Last() != nil {
+		lastError = genErr.Last().Error()
+	}
+
+	// A missing book will never succeed, so don't retry it
+	retryable := !errors.Is(err, gorm.ErrRecordNotFound)
+
+	if retryable && job.Attempts < job.MaxAttempts {
+		delay := jobRetryDelay(job.Attempts)
+		log.Printf("🔁 Quiz job %s failed (attempt %d/%d), retrying in %v: %v", job.ID, job.Attempts, job.MaxAttempts, delay, err)
+
+		w.finishJob(job, models.JobStatusQueued, lastError, time.Now().Add(delay))
+		setBookQuizStatus(job.BookID, "pending")
+		return
+	}
+
+	log.Printf("❌ Quiz job %s failed permanently after %d attempts: %v", job.ID, job.Attempts, err)
+	w.finishJob(job, models.JobStatusFailed, lastError, time.Time{})
+	w.recordFailedQuiz(job, err)
+}
+
+// heartbeat refreshes the lock of a running job every third of
+// QUIZ_JOB_LOCK_TIMEOUT until done is closed
+func (w *QuizWorker) heartbeat(jobID uuid.UUID, done <-chan struct{}) {
+	ticker := time.NewTicker(w.lockTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			result := database.DB.Model(&models.QuizJob{}).
+				Where("id = ? AND status = ? AND locked_by = ?", jobID, models.JobStatusRunning, w.workerID).
+				Update("locked_at", time.Now())
+			if result.Error != nil {
+				log.Printf("⚠️ Failed to refresh lock of quiz job %s: %v", jobID, result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				log.Printf("⚠️ Quiz job %s is no longer locked by this worker", jobID)
+				return
+			}
+		}
+	}
+}
+
+// finishAttempt stores the outcome of a job attempt
+func (w *QuizWorker) finishAttempt(attempt *models.QuizJobAttempt, err error) {
+	if attempt.ID == uuid.Nil {
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.AttemptStatusSucceeded,
+		"finished_at": now,
+	}
+
+	if err != nil {
+		updates["status"] = models.AttemptStatusFailed
+		if errors.Is(err, ErrJobCancelled) {
+			updates["status"] = models.AttemptStatusCancelled
+		}
+		updates["error"] = err.Error()
+
+		var genErr *GenerationError
+		if errors.As(err, &genErr) {
+			updates["generation_errors"] = pq.StringArray(genErr.Messages())
+		}
+	}
+
+	if dbErr := database.DB.Model(attempt).Updates(updates).Error; dbErr != nil {
+		log.Printf("❌ Failed to update attempt %s: %v", attempt.ID, dbErr)
 	}
+}
+
+// finishJob releases the job lock and stores its new status
+func (w *QuizWorker) finishJob(job *models.QuizJob, status, lastError string, runAfter time.Time) {
+	updates := map[string]interface{}{
+		"status":     status,
+		"last_error": lastError,
+		"locked_by":  "",
+		"locked_at":  nil,
+	}
+	if !runAfter.IsZero() {
+		updates["run_after"] = runAfter
+	}
+	if status == models.JobStatusCompleted || status == models.JobStatusFailed || status == models.JobStatusCancelled {
+		updates["finished_at"] = time.Now()
+	}
+
+	if err := database.DB.Model(job).Updates(updates).Error; err != nil {
+		log.Printf("❌ Failed to update quiz job %s: %v", job.ID, err)
+	}
+}
+
+// cancelRequested reports whether cancellation was requested for a running job
+func (w *QuizWorker) cancelRequested(jobID uuid.UUID) bool {
+	var job models.QuizJob
+	if err := database.DB.Select("cancel_requested").Where("id = ?", jobID).First(&job).Error; err != nil {
+		return false
+	}
+	return job.CancelRequested
+}
+
+// resetGeneratingBook puts a book that was left "generating" back to "pending"
+func (w *QuizWorker) resetGeneratingBook(bookID uuid.UUID) {
+	database.DB.Model(&models.Book{}).
+		Where("id = ? AND quiz_status = ? AND quiz_id IS NULL", bookID, "generating").
+		Update("quiz_status", "pending")
+}
+
+// setBookQuizStatus updates the quiz status of a book without an active quiz.
+// Books with an active quiz stay "completed" while a new version is generated,
+// so their current quiz remains available.
+func setBookQuizStatus(bookID uuid.UUID, status string) {
+	database.DB.Model(&models.Book{}).
+		Where("id = ? AND quiz_id IS NULL", bookID).
+		Update("quiz_status", status)
+}
 
-	log.Printf("👷 Worker #%d stopped", id)
+// jobRetryDelay returns the backoff before the next attempt
+func jobRetryDelay(attempts int) time.Duration {
+	delay := time.Duration(attempts*attempts) * time.Minute
+	if delay > 30*time.Minute {
+		delay = 30 * time.Minute
+	}
+	return delay
 }
 
-// processQuizGeneration generates a quiz for a book
-func (w *QuizWorker) processQuizGeneration(bookID uuid.UUID) {
+// processQuizGeneration generates a quiz for the job's book
+func (w *QuizWorker) processQuizGeneration(job *models.QuizJob) error {
+	bookID := job.BookID
+
 	// Get book from database
 	var book models.Book
 	if err := database.DB.Where("id = ?", bookID).First(&book).Error; err != nil {
-		log.Printf("❌ Failed to get book %s: %v", bookID, err)
-		return
+		return fmt.Errorf("failed to get book %s: %w", bookID, err)
 	}
 
-	// Check if quiz already exists and is completed
-	var existingQuiz models.Quiz
-	if err := database.DB.Where("book_id = ? AND status = ?", bookID, "completed").First(&existingQuiz).Error; err == nil {
-		log.Printf("ℹ️ Quiz already exists for book '%s', skipping", book.Title)
-		
-		// Update book quiz status
-		database.DB.Model(&book).Updates(map[string]interface{}{
-			"quiz_id":     existingQuiz.ID,
-			"quiz_status": "completed",
-		})
-		return
+	params := w.generator.ResolveQuizParams(job.QuizParams)
+
+	// Unless regeneration is forced, keep an existing quiz with the same parameters
+	if !job.Force {
+		if existingQuiz, err := FindQuiz(bookID, params); err == nil {
+			log.Printf("ℹ️ %s/%s quiz already exists for book '%s', skipping", params.Difficulty, params.Language, book.Title)
+
+			// Update book quiz status, keeping an explicitly activated version
+			if book.QuizID == nil {
+				database.DB.Model(&book).Updates(map[string]interface{}{
+					"quiz_id":     existingQuiz.ID,
+					"quiz_status": "completed",
+				})
+			}
+			return nil
+		}
 	}
-	
-	// Delete failed quiz if exists
-	database.DB.Where("book_id = ? AND status = ?", bookID, "failed").Delete(&models.Quiz{})
 
 	// Update book status to "generating"
-	database.DB.Model(&book).Update("quiz_status", "generating")
+	setBookQuizStatus(bookID, "generating")
 
 	// Generate quiz
-	quiz, err := w.generator.GenerateQuiz(&book)
+	quiz, err := w.generator.GenerateQuiz(&book, params)
 	if err != nil {
-		log.Printf("❌ Failed to generate quiz for book '%s': %v", book.Title, err)
-		
-		// Update book status to "failed"
-		database.DB.Model(&book).Updates(map[string]interface{}{
-			"quiz_status": "failed",
-		})
-		
-	// Create failed quiz record for tracking
+		return fmt.Errorf("failed to generate quiz for book '%s': %w", book.Title, err)
+	}
+
+	// Generation can take a while; honor cancellation requested meanwhile
+	if w.cancelRequested(job.ID) {
+		return ErrJobCancelled
+	}
+
+	// Delete failed quiz of this difficulty and language if exists
+	database.DB.Where("book_id = ? AND status = ? AND difficulty = ? AND language = ?", bookID, "failed", params.Difficulty, params.Language).Delete(&models.Quiz{})
+
+	// Save quiz as the next version, active unless it would replace another difficulty or language
+	if err := saveQuizVersion(quiz); err != nil {
+		return err
+	}
+
+	log.Printf("✅ %s/%s quiz generated and saved for book '%s' (quiz_id: %s, version: %d)", params.Difficulty, params.Language, book.Title, quiz.ID, quiz.Version)
+	return nil
+}
+
+// recordFailedQuiz marks the book as failed and stores a failed quiz record for tracking
+func (w *QuizWorker) recordFailedQuiz(job *models.QuizJob, cause error) {
+	bookID := job.BookID
+	setBookQuizStatus(bookID, "failed")
+
+	// Replace any previous failed record of this difficulty and language
+	database.DB.Where("book_id = ? AND status = ? AND difficulty = ? AND language = ?", bookID, "failed", job.Difficulty, job.Language).Delete(&models.Quiz{})
+
 	failedQuiz := &models.Quiz{
 		BookID:     bookID,
+		QuizParams: w.generator.ResolveQuizParams(job.QuizParams),
 		Questions:  datatypes.JSON([]byte(`{"quiz":[]}`)),
-		AIModel:    w.generator.modelName,
+		AIModel:    w.generator.ModelID(),
 		Status:     "failed",
 		RetryCount: w.generator.retryLimit,
-		ErrorLog:   err.Error(),
+		ErrorLog:   cause.Error(),
 	}
-		database.DB.Create(failedQuiz)
-		return
+	var genErr *GenerationError
+	if errors.As(cause, &genErr) {
+		failedQuiz.ErrorLog = genErr.Log()
+	}
+	if err := database.DB.Create(failedQuiz).Error; err != nil {
+		log.Printf("❌ Failed to store failed quiz record for book %s: %v", bookID, err)
 	}
+}
 
-	// Save quiz to database
-	if err := database.DB.Create(quiz).Error; err != nil {
-		log.Printf("❌ Failed to save quiz to database: %v", err)
-		
-		database.DB.Model(&book).Update("quiz_status", "failed")
+// recoverStaleJobs requeues running jobs whose lock has expired, e.g.
+// because the replica that claimed them crashed
+func (w *QuizWorker) recoverStaleJobs() {
+	var staleJobs []models.QuizJob
+	if err := database.DB.Where("status = ? AND locked_at < ?", models.JobStatusRunning, time.Now().Add(-w.lockTimeout)).
+		Find(&staleJobs).Error; err != nil {
+		log.Printf("❌ Failed to find stale quiz jobs: %v", err)
 		return
 	}
 
-	// Update book with quiz ID and status
-	database.DB.Model(&book).Updates(map[string]interface{}{
-		"quiz_id":     quiz.ID,
-		"quiz_status": "completed",
-	})
+	for i := range staleJobs {
+		job := &staleJobs[i]
+		log.Printf("♻️ Requeueing stale quiz job %s (locked by %s)", job.ID, job.LockedBy)
+
+		w.finishJob(job, models.JobStatusQueued, "job lock expired", time.Now())
+		database.DB.Model(&models.QuizJobAttempt{}).
+			Where("job_id = ? AND status = ?", job.ID, models.AttemptStatusRunning).
+			Updates(map[string]interface{}{
+				"status":      models.AttemptStatusFailed,
+				"error":       "job lock expired",
+				"finished_at": time.Now(),
+			})
+		w.resetGeneratingBook(job.BookID)
+	}
+}
+
+// staleJobMonitor periodically recovers stale jobs until the worker stops
+func (w *QuizWorker) staleJobMonitor() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.lockTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.recoverStaleJobs()
+		}
+	}
+}
+
+// FindQuiz returns the book's newest completed quiz generated with params,
+// resolving a question count of 0 to QUIZ_QUESTIONS_COUNT
+func (w *QuizWorker) FindQuiz(bookID uuid.UUID, params models.QuizParams) (*models.Quiz, error) {
+	return FindQuiz(bookID, w.generator.ResolveQuizParams(params))
+}
+
+// ActiveJob returns the book's queued or running job for a difficulty and
+// language; empty values match any
+func ActiveJob(bookID uuid.UUID, difficulty, language string) (*models.QuizJob, error) {
+	var job models.QuizJob
+	err := quizVariant(database.DB.Where("book_id = ? AND status IN ?", bookID, []string{models.JobStatusQueued, models.JobStatusRunning}), difficulty, language).
+		Order("created_at DESC").
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJob returns a quiz job with its attempt history
+func (w *QuizWorker) GetJob(jobID uuid.UUID) (*models.QuizJob, error) {
+	var job models.QuizJob
+	err := database.DB.Preload("AttemptHistory", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).Where("id = ?", jobID).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelJob cancels a queued job immediately. A running job is flagged so
+// the worker discards its result once the current generation returns.
+func (w *QuizWorker) CancelJob(jobID uuid.UUID) (*models.QuizJob, error) {
+	now := time.Now()
+
+	result := database.DB.Model(&models.QuizJob{}).
+		Where("id = ? AND status = ?", jobID, models.JobStatusQueued).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusCancelled,
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		result = database.DB.Model(&models.QuizJob{}).
+			Where("id = ? AND status = ?", jobID, models.JobStatusRunning).
+			Update("cancel_requested", true)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+
+	job, err := w.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return job, ErrJobNotCancellable
+	}
+
+	log.Printf("🚫 Quiz job %s cancelled (status: %s)", job.ID, job.Status)
+	return job, nil
+}
+
+// RetryJob requeues a failed or cancelled job with a fresh attempt budget
+func (w *QuizWorker) RetryJob(jobID uuid.UUID) (*models.QuizJob, error) {
+	job, err := w.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusFailed && job.Status != models.JobStatusCancelled {
+		return job, ErrJobNotRetryable
+	}
+
+	var active int64
+	if err := database.DB.Model(&models.QuizJob{}).
+		Where("book_id = ? AND difficulty = ? AND language = ? AND status IN ?", job.BookID, job.Difficulty, job.Language, []string{models.JobStatusQueued, models.JobStatusRunning}).
+		Count(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to check active quiz jobs: %w", err)
+	}
+	if active > 0 {
+		return job, ErrJobAlreadyActive
+	}
+
+	result := database.DB.Model(&models.QuizJob{}).
+		Where("id = ? AND status = ?", job.ID, job.Status).
+		Updates(map[string]interface{}{
+			"status":           models.JobStatusQueued,
+			"attempts":         0,
+			"run_after":        time.Now(),
+			"cancel_requested": false,
+			"finished_at":      nil,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return job, ErrJobNotRetryable
+	}
+
+	database.DB.Model(&models.Book{}).
+		Where("id = ? AND quiz_status = ?", job.BookID, "failed").
+		Update("quiz_status", "pending")
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
 
-	log.Printf("✅ Quiz generated and saved for book '%s' (quiz_id: %s)", book.Title, quiz.ID)
+	log.Printf("🔄 Quiz job %s requeued", job.ID)
+	return w.GetJob(jobID)
 }
 
-// ProcessPendingQuizzes processes all books with pending quiz status
-func (w *QuizWorker) ProcessPendingQuizzes() {
+// ProcessPendingQuizzes enqueues quiz generation for pending and failed
+// books and returns the number of books enqueued
+func (w *QuizWorker) ProcessPendingQuizzes() (int, error) {
 	var books []models.Book
 	if err := database.DB.Where("quiz_status IN ?", []string{"pending", "failed"}).Find(&books).Error; err != nil {
 		log.Printf("❌ Failed to get pending books: %v", err)
-		return
+		return 0, err
 	}
 
 	if len(books) == 0 {
 		log.Println("ℹ️ No pending quizzes to process")
-		return
+		return 0, nil
 	}
 
 	log.Printf("📚 Found %d books with pending quizzes", len(books))
 
+	enqueued := 0
 	for _, book := range books {
-		w.Enqueue(book.ID)
+		if _, err := w.Enqueue(book.ID, QuizJobOptions{}); err != nil {
+			log.Printf("❌ Failed to enqueue book %s: %v", book.ID, err)
+			continue
+		}
+		enqueued++
 	}
+	return enqueued, nil
 }
 
-// RetryFailedQuizzes retries quiz generation for failed books
-func (w *QuizWorker) RetryFailedQuizzes() {
+// RetryFailedQuizzes retries quiz generation for failed books and returns
+// the number of books enqueued
+func (w *QuizWorker) RetryFailedQuizzes() (int, error) {
 	var books []models.Book
 	if err := database.DB.Where("quiz_status = ?", "failed").Find(&books).Error; err != nil {
 		log.Printf("❌ Failed to get failed books: %v", err)
-		return
+		return 0, err
 	}
 
 	if len(books) == 0 {
 		log.Println("ℹ️ No failed quizzes to retry")
-		return
+		return 0, nil
 	}
 
 	log.Printf("🔄 Retrying %d failed quizzes", len(books))
 
+	enqueued := 0
 	for _, book := range books {
 		// Reset status to pending
 		database.DB.Model(&book).Update("quiz_status", "pending")
-		w.Enqueue(book.ID)
+		if _, err := w.Enqueue(book.ID, QuizJobOptions{}); err != nil {
+			log.Printf("❌ Failed to enqueue book %s: %v", book.ID, err)
+			continue
+		}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// ResetFailedQuizzes puts failed books back to "pending" and deletes their
+// failed quiz records without enqueueing new jobs. It returns the number of
+// books reset.
+func (w *QuizWorker) ResetFailedQuizzes() (int, error) {
+	var reset int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status = ? AND book_id IN (?)", "failed",
+			tx.Model(&models.Book{}).Select("id").Where("quiz_status = ?", "failed"),
+		).Delete(&models.Quiz{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Book{}).Where("quiz_status = ?", "failed").Update("quiz_status", "pending")
+		reset = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
 	}
+
+	log.Printf("♻️ Reset %d failed quizzes to pending", reset)
+	return int(reset), nil
 }
 
 // StartPeriodicRetry starts a periodic retry mechanism for failed quizzes
@@ -224,30 +848,63 @@ func (w *QuizWorker) StartPeriodicRetry(interval time.Duration) {
 	log.Printf("⏰ Periodic retry started (interval: %v)", interval)
 }
 
-// GetQueueSize returns the current queue size
+// ReloadPrompts reloads the quiz prompt templates from disk. On error the
+// templates in use are kept.
+func (w *QuizWorker) ReloadPrompts() error {
+	return w.generator.Prompts().Reload()
+}
+
+// Running reports whether the worker pool is started
+func (w *QuizWorker) Running() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.running
+}
+
+// Stopping reports whether the worker pool was stopped but some workers
+// are still finishing their jobs
+func (w *QuizWorker) Stopping() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stoppingLocked()
+}
+
+// GetQueueSize returns the number of queued jobs
 func (w *QuizWorker) GetQueueSize() int {
-	return len(w.queue)
+	var queued int64
+	database.DB.Model(&models.QuizJob{}).Where("status = ?", models.JobStatusQueued).Count(&queued)
+	return int(queued)
 }
 
 // GetStats returns worker statistics
 func (w *QuizWorker) GetStats() map[string]interface{} {
-	var total, pending, generating, completed, failed int64
+	var total, pending, generating, completed, failed, runningJobs int64
 
 	database.DB.Model(&models.Book{}).Count(&total)
 	database.DB.Model(&models.Book{}).Where("quiz_status = ?", "pending").Count(&pending)
 	database.DB.Model(&models.Book{}).Where("quiz_status = ?", "generating").Count(&generating)
 	database.DB.Model(&models.Book{}).Where("quiz_status = ?", "completed").Count(&completed)
 	database.DB.Model(&models.Book{}).Where("quiz_status = ?", "failed").Count(&failed)
+	database.DB.Model(&models.QuizJob{}).Where("status = ?", models.JobStatusRunning).Count(&runningJobs)
+
+	running := w.Running()
+	stopping := w.Stopping()
 
 	return map[string]interface{}{
-		"total_books":    total,
-		"pending":        pending,
-		"generating":     generating,
-		"completed":      completed,
-		"failed":         failed,
-		"queue_size":     w.GetQueueSize(),
-		"worker_count":   w.workerCount,
-		"worker_running": w.running,
+		"total_books":     total,
+		"pending":         pending,
+		"generating":      generating,
+		"completed":       completed,
+		"failed":          failed,
+		"queue_size":      w.GetQueueSize(),
+		"running_jobs":    runningJobs,
+		"worker_count":    w.workerCount,
+		"worker_running":  running,
+		"worker_stopping": stopping,
+		"worker_id":       w.workerID,
+		"llm_budget":      w.generator.Budget().Stats(),
+		"llm_paused":      w.budgetPaused.Load(),
+		"prompts":         w.generator.Prompts().Versions(),
 	}
 }
 
@@ -257,4 +914,3 @@ func (w *QuizWorker) PrettyPrintStats() {
 	statsJSON, _ := json.MarshalIndent(stats, "", "  ")
 	log.Printf("📊 Quiz Worker Stats:\n%s", string(statsJSON))
 }
-
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestQuizWorkerStop checks that stopping doesn't hold the worker lock
+// while a job is still running
+func TestQuizWorkerStop(t *testing.T) {
+	stopped := make(chan struct{})
+	close(stopped)
+	w := &QuizWorker{running: true, stop: make(chan struct{}), stopped: stopped}
+
+	// A worker busy with a job until released
+	release := make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		<-w.stop
+		<-release
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !w.Stopping() {
+		if time.Now().After(deadline) {
+			t.Fatal("worker never reported stopping")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if w.Running() {
+		t.Error("Running() = true while stopping")
+	}
+	if err := w.Start(); !errors.Is(err, ErrWorkerStopping) {
+		t.Errorf("Start() while stopping = %v, want ErrWorkerStopping", err)
+	}
+	select {
+	case <-w.BeginStop():
+		t.Error("BeginStop() channel closed before the job finished")
+	case <-done:
+		t.Error("Stop() returned before the job finished")
+	default:
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() did not return after the job finished")
+	}
+	if w.Stopping() || w.Running() {
+		t.Errorf("after Stop(): stopping = %v, running = %v", w.Stopping(), w.Running())
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bookwise/api/config"
+	"github.com/bookwise/api/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// SearchCache stores provider search results. An empty, non-nil result
+// is a cached "not found" answer.
+type SearchCache interface {
+	Get(ctx context.Context, key string) ([]*BookData, bool)
+	Set(ctx context.Context, key string, books []*BookData, ttl time.Duration)
+}
+
+// NewSearchCacheFromConfig creates the cache selected by
+// SEARCH_CACHE_BACKEND, or nil when caching is disabled. The redis backend
+// requires database.InitRedis first.
+func NewSearchCacheFromConfig(cfg *config.Config) (SearchCache, error) {
+	if !cfg.SearchCache.Enabled {
+		log.Println("ℹ️ Search result cache is disabled")
+		return nil, nil
+	}
+
+	switch cfg.SearchCache.Backend {
+	case "memory":
+		log.Printf("🗃️ Search result cache: memory (max %d entries, ttl %v)", cfg.SearchCache.MaxEntries, cfg.SearchCache.TTL)
+		return NewMemorySearchCache(cfg.SearchCache.MaxEntries), nil
+	case "redis":
+		log.Printf("🗃️ Search result cache: redis (ttl %v)", cfg.SearchCache.TTL)
+		return NewRedisSearchCache(), nil
+	default:
+		return nil, fmt.Errorf("unknown SEARCH_CACHE_BACKEND %q (expected memory or redis)", cfg.SearchCache.Backend)
+	}
+}
+
+// searchCacheKey builds the cache key of a provider search. mode is
+// "single" for one-result lookups or the result limit for list searches.
+func searchCacheKey(provider, searchType, mode, query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return fmt.Sprintf("search:%s:%s:%s:%s", provider, searchType, mode, query)
+}
+
+// cloneBooks copies results so cached entries aren't shared with callers
+func cloneBooks(books []*BookData) []*BookData {
+	clones := make([]*BookData, len(books))
+	for i, book := range books {
+		clone := *book
+		clone.Authors = append([]string(nil), book.Authors...)
+		clone.Categories = append([]string(nil), book.Categories...)
+		clones[i] = &clone
+	}
+	return clones
+}
+
+// memorySearchEntry is an element of the memory cache LRU list
+type memorySearchEntry struct {
+	key       string
+	books     []*BookData
+	expiresAt time.Time
+}
+
+// MemorySearchCache is an in-process LRU cache with per-entry TTL
+type MemorySearchCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // Front is most recently used
+	entries    map[string]*list.Element
+}
+
+// NewMemorySearchCache creates an LRU cache holding up to maxEntries results
+func NewMemorySearchCache(maxEntries int) *MemorySearchCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &MemorySearchCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get implements SearchCache
+func (c *MemorySearchCache) Get(ctx context.Context, key string) ([]*BookData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memorySearchEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return cloneBooks(entry.books), true
+}
+
+// Set implements SearchCache
+func (c *MemorySearchCache) Set(ctx context.Context, key string, books []*BookData, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memorySearchEntry{key: key, books: cloneBooks(books), expiresAt: time.Now().Add(ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memorySearchEntry).key)
+	}
+}
+
+// RedisSearchCache stores results as JSON in Redis, shared by all replicas
+type RedisSearchCache struct{}
+
+// NewRedisSearchCache creates a cache using database.Redis
+func NewRedisSearchCache() *RedisSearchCache {
+	return &RedisSearchCache{}
+}
+
+// Get implements SearchCache. Redis errors are logged and count as a miss.
+func (c *RedisSearchCache) Get(ctx context.Context, key string) ([]*BookData, bool) {
+	if database.Redis == nil {
+		return nil, false
+	}
+
+	data, err := database.Redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️ Search cache read failed for %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	books := []*BookData{}
+	if err := json.Unmarshal(data, &books); err != nil {
+		log.Printf("⚠️ Discarding corrupt search cache entry %s: %v", key, err)
+		return nil, false
+	}
+	return books, true
+}
+
+// Set implements SearchCache
+func (c *RedisSearchCache) Set(ctx context.Context, key string, books []*BookData, ttl time.Duration) {
+	if database.Redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(books)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode search cache entry %s: %v", key, err)
+		return
+	}
+	if err := database.Redis.Set(ctx, key, data, ttl).Err(); err != nil {
+		log.Printf("⚠️ Search cache write failed for %s: %v", key, err)
+	}
+}